@@ -2,15 +2,236 @@ package alert
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sentinel/config"
+)
+
+func init() {
+	Register("discord", newDiscordNotifier)
+}
+
+const (
+	// discordEmbedsPerMessage is Discord's limit of embeds in one message.
+	discordEmbedsPerMessage = 10
+	// discordMessageChars is Discord's limit of characters in all the
+	// embeds of one message.
+	discordMessageChars = 6000
+	// discordMaxRetries bounds how often a rate-limited request is retried.
+	discordMaxRetries = 3
 )
 
-func SendDiscord(webhookURL, msg string) error {
-	if webhookURL == "" {
-		return nil
+var discordClient = &http.Client{Timeout: 10 * time.Second}
+
+// discordNotifier posts alerts to a Discord webhook as embeds.
+type discordNotifier struct {
+	name string
+	url  string
+}
+
+type discordSettings struct {
+	URL string `json:"url"`
+}
+
+func newDiscordNotifier(name string, raw json.RawMessage) (Notifier, error) {
+	var s discordSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		return nil, fmt.Errorf("settings: url is required")
 	}
+	return NewDiscord(name, s.URL), nil
+}
+
+// NewDiscord returns a notifier for a Discord webhook URL.
+func NewDiscord(name, webhookURL string) Notifier {
+	return &discordNotifier{name: name, url: webhookURL}
+}
 
-	body := []byte(`{"content":"` + msg + `"}`)
-	_, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(body))
-	return err
-}
\ No newline at end of file
+func (n *discordNotifier) Name() string { return n.name }
+
+func (n *discordNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	sent := 0
+	for len(alerts) > 0 {
+		// A message ends at the embed or character limit, whichever comes
+		// first. An embed over the character limit by itself goes alone.
+		var msg discordPayload
+		chars := 0
+		for _, a := range alerts {
+			e := discordAlertEmbed(a)
+			if len(msg.Embeds) == discordEmbedsPerMessage ||
+				len(msg.Embeds) > 0 && chars+e.chars() > discordMessageChars {
+				break
+			}
+			msg.Embeds = append(msg.Embeds, e)
+			chars += e.chars()
+		}
+		alerts = alerts[len(msg.Embeds):]
+
+		if err := postDiscord(ctx, n.url, msg); err != nil {
+			return partial(sent, err)
+		}
+		sent += len(msg.Embeds)
+	}
+	return nil
+}
+
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+// chars counts the characters of the embed the way Discord applies its
+// per-message limit: title, description, field names and values, footer.
+func (e *discordEmbed) chars() int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return n
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// Embed colors.
+const (
+	colorResolved = 0x2ecc71
+	colorInfo     = 0x3498db
+	colorWarning  = 0xf39c12
+	colorCritical = 0xe74c3c
+)
+
+func severityColor(a Alert) int {
+	if a.Resolved {
+		return colorResolved
+	}
+	switch a.Severity {
+	case config.SeverityInfo:
+		return colorInfo
+	case config.SeverityCritical:
+		return colorCritical
+	}
+	return colorWarning
+}
+
+func discordAlertEmbed(a Alert) discordEmbed {
+	e := discordEmbed{
+		Title:  truncateText(a.Text, 256),
+		Color:  severityColor(a),
+		Footer: &discordFooter{Text: fmt.Sprintf("%s · %s", a.Rule, a.Severity)},
+	}
+	if !a.Time.IsZero() {
+		e.Timestamp = a.Time.UTC().Format(time.RFC3339)
+	}
+
+	add := func(name, value string, inline bool) {
+		if value == "" {
+			return
+		}
+		e.Fields = append(e.Fields, discordField{Name: name, Value: truncateText(value, 1024), Inline: inline})
+	}
+
+	if a.Pid != 0 {
+		add("PID", strconv.Itoa(a.Pid), true)
+		add("User", a.User, true)
+		add("Process", a.Comm, true)
+		add("CPU", fmt.Sprintf("%.1f%%", a.CPU), true)
+		add("MEM", fmt.Sprintf("%.1f%%", a.PMem), true)
+	}
+	if a.Metric != "" {
+		add("Value", fmt.Sprintf("%s = %.1f (threshold %g)", a.Metric, a.Value, a.Threshold), false)
+	} else if a.Expr != "" {
+		add("Condition", "`"+a.Expr+"`", false)
+	}
+	add("Host", a.Host, true)
+
+	if a.Cmd != "" {
+		// A run of backticks inside the command could close the code
+		// block: a zero-width joiner after each one keeps them apart
+		cmd := strings.ReplaceAll(truncateText(a.Cmd, 1000), "`", "`\u200d")
+		e.Description = "```\n" + cmd + "\n```"
+	}
+	return e
+}
+
+// postDiscord sends a payload, waiting and retrying when Discord answers
+// 429 Too Many Requests. Other non-2xx answers are returned as errors, see
+// statusError.
+func postDiscord(ctx context.Context, webhookURL string, msg discordPayload) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := discordClient.Do(req)
+		if err != nil {
+			return err
+		}
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= discordMaxRetries {
+			return statusError("discord", resp, reply)
+		}
+
+		wait := discordRetryAfter(resp.Header, reply)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("discord: rate limited for %s: %w", wait, ctx.Err())
+		}
+	}
+}
+
+// discordRetryAfter reads the wait time of a 429 answer from the JSON body
+// ("retry_after" in seconds) or the Retry-After header.
+func discordRetryAfter(h http.Header, body []byte) time.Duration {
+	var rl struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &rl) == nil && rl.RetryAfter > 0 {
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return time.Second
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// discordPayloads decodes the messages received by a recordingServer.
+func discordPayloads(t *testing.T, reqs []recordedRequest) []discordPayload {
+	t.Helper()
+	out := make([]discordPayload, len(reqs))
+	for i, r := range reqs {
+		r.decode(t, &out[i])
+	}
+	return out
+}
+
+func TestDiscordCommandEscaping(t *testing.T) {
+	srv, reqs := recordingServer(t)
+	a := testAlert(1)
+	a.Cmd = "sh -c \"echo \\\"hi\\\" C:\\\\tmp ``` ```` `x`\""
+	if err := NewDiscord("ops", srv.URL).Notify(context.Background(), []Alert{a}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := discordPayloads(t, *reqs)
+	if len(got) != 1 || len(got[0].Embeds) != 1 {
+		t.Fatalf("payloads %+v, want one embed", got)
+	}
+	desc := got[0].Embeds[0].Description
+	if n := strings.Count(desc, "``"); n != 2 || !strings.HasPrefix(desc, "```\n") || !strings.HasSuffix(desc, "\n```") {
+		t.Errorf("description %q, want backticks only in the fences", desc)
+	}
+	if cmd := strings.ReplaceAll(strings.Trim(desc, "`\n"), "\u200d", ""); cmd != a.Cmd {
+		t.Errorf("command %q, want %q", cmd, a.Cmd)
+	}
+}
+
+func TestDiscordSplitsMessages(t *testing.T) {
+	srv, reqs := recordingServer(t)
+	if err := NewDiscord("ops", srv.URL).Notify(context.Background(), testAlerts(discordEmbedsPerMessage+2)); err != nil {
+		t.Fatal(err)
+	}
+	got := discordPayloads(t, *reqs)
+	if len(got) != 2 || len(got[0].Embeds) != discordEmbedsPerMessage || len(got[1].Embeds) != 2 {
+		t.Fatalf("%d messages, want %d embeds, then 2", len(got), discordEmbedsPerMessage)
+	}
+
+	// Long commands reach the character limit before the embed limit
+	*reqs = nil
+	alerts := testAlerts(8)
+	for i := range alerts {
+		alerts[i].Cmd = strings.Repeat("x", 1000)
+	}
+	if err := NewDiscord("ops", srv.URL).Notify(context.Background(), alerts); err != nil {
+		t.Fatal(err)
+	}
+	got = discordPayloads(t, *reqs)
+	embeds := 0
+	for _, p := range got {
+		chars := 0
+		for i := range p.Embeds {
+			chars += p.Embeds[i].chars()
+		}
+		if chars > discordMessageChars {
+			t.Errorf("message of %d characters", chars)
+		}
+		embeds += len(p.Embeds)
+	}
+	if len(got) < 2 || embeds != len(alerts) {
+		t.Errorf("%d embeds in %d messages, want %d in several", embeds, len(got), len(alerts))
+	}
+}
+
+func TestDiscordRateLimit(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"message": "You are being rate limited.", "retry_after": 0.05, "global": false}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	if err := NewDiscord("ops", srv.URL).Notify(context.Background(), testAlerts(1)); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want a retry after the 429", requests)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > time.Second {
+		t.Errorf("waited %s, want retry_after", waited)
+	}
+}
+
+func TestDiscordErrors(t *testing.T) {
+	srv, _ := recordingServer(t, http.StatusBadRequest)
+	err := NewDiscord("ops", srv.URL).Notify(context.Background(), testAlerts(1))
+	if err == nil || !strings.Contains(err.Error(), "invalid_payload") || !IsPermanent(err) {
+		t.Errorf("400: error %v, want a permanent error with the reason", err)
+	}
+
+	// A failed second message reports the first as sent
+	srv, _ = recordingServer(t, http.StatusNoContent, http.StatusBadGateway)
+	err = NewDiscord("ops", srv.URL).Notify(context.Background(), testAlerts(discordEmbedsPerMessage+1))
+	var p *PartialError
+	if !errors.As(err, &p) || p.Sent != discordEmbedsPerMessage || IsPermanent(err) {
+		t.Errorf("502: error %v, want a transient PartialError after %d alerts", err, discordEmbedsPerMessage)
+	}
+}
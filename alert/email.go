@@ -0,0 +1,311 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("email", newEmailNotifier)
+}
+
+// emailSettings configures an SMTP channel. TLS is "starttls" (default,
+// required) or "none" for local relays and test sinks.
+type emailSettings struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"` // default 587
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	TLS      string   `json:"tls,omitempty"`
+}
+
+// emailNotifier sends all alerts of a tick as one multipart text+HTML mail.
+type emailNotifier struct {
+	name  string
+	cfg   emailSettings
+	from  *mail.Address
+	to    []*mail.Address
+	roots *x509.CertPool // trusted for STARTTLS, nil for the system roots
+}
+
+func newEmailNotifier(name string, raw json.RawMessage) (Notifier, error) {
+	var s emailSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+
+	if s.Host == "" {
+		return nil, fmt.Errorf("settings: host is required")
+	}
+	if s.Port == 0 {
+		s.Port = 587
+	}
+	switch s.TLS {
+	case "":
+		s.TLS = "starttls"
+	case "starttls", "none":
+	default:
+		return nil, fmt.Errorf("settings: tls must be \"starttls\" or \"none\", got %q", s.TLS)
+	}
+
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return nil, fmt.Errorf("settings: from: %w", err)
+	}
+	if len(s.To) == 0 {
+		return nil, fmt.Errorf("settings: to needs at least one recipient")
+	}
+	to := make([]*mail.Address, 0, len(s.To))
+	for _, addr := range s.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("settings: to: %w", err)
+		}
+		to = append(to, a)
+	}
+
+	return &emailNotifier{name: name, cfg: s, from: from, to: to}, nil
+}
+
+func (n *emailNotifier) Name() string { return n.name }
+
+func (n *emailNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	msg, err := n.compose(alerts, time.Now())
+	if err != nil {
+		return Permanent(err)
+	}
+	err = n.send(ctx, msg)
+
+	// 5xx replies reject the sender, a recipient or the message for good
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code/100 == 5 {
+		return Permanent(err)
+	}
+	return err
+}
+
+// send delivers msg through the configured SMTP server.
+func (n *emailNotifier) send(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.cfg.TLS == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return Permanent(fmt.Errorf("smtp: %s does not support STARTTLS", addr))
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: n.cfg.Host, RootCAs: n.roots}); err != nil {
+			return err
+		}
+	}
+
+	if n.cfg.Username != "" {
+		// PlainAuth refuses to send credentials without TLS except to localhost
+		auth := smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(n.from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range n.to {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("smtp: recipient %s: %w", rcpt.Address, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose builds a multipart/alternative message with a plain-text and an
+// HTML rendering of the alerts.
+func (n *emailNotifier) compose(alerts []Alert, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	to := make([]string, len(n.to))
+	for i, a := range n.to {
+		to[i] = a.String()
+	}
+
+	hdr := []struct{ key, value string }{
+		{"From", n.from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", emailSubject(alerts))},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(n.from.Address)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range hdr {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, alerts); err != nil {
+		return nil, err
+	}
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", emailText(alerts)},
+		{"text/html; charset=utf-8", html.String()},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(p.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func emailSubject(alerts []Alert) string {
+	host := alerts[0].Host
+	if len(alerts) == 1 {
+		a := alerts[0]
+		s := "[sentinel] " + a.Text
+		if host != "" {
+			s += " on " + host
+		}
+		return truncateText(s, 150)
+	}
+
+	firing := 0
+	for _, a := range alerts {
+		if !a.Resolved {
+			firing++
+		}
+	}
+	s := fmt.Sprintf("[sentinel] %d alerts (%d firing, %d resolved)", len(alerts), firing, len(alerts)-firing)
+	if host != "" {
+		s += " on " + host
+	}
+	return s
+}
+
+func emailText(alerts []Alert) string {
+	var b strings.Builder
+	for i, a := range alerts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(a.Text + "\n")
+		for _, f := range alertDetails(a) {
+			fmt.Fprintf(&b, "  %-10s %s\n", f[0]+":", f[1])
+		}
+	}
+	return b.String()
+}
+
+// alertDetails returns the label/value pairs shown under each alert.
+func alertDetails(a Alert) [][2]string {
+	var out [][2]string
+	add := func(label, value string) {
+		out = append(out, [2]string{label, value})
+	}
+
+	add("Rule", fmt.Sprintf("%s (%s)", a.Rule, a.Severity))
+	if a.Pid != 0 {
+		add("Process", fmt.Sprintf("%s (PID %d, user %s)", a.Comm, a.Pid, a.User))
+		if a.Cmd != "" {
+			add("Command", a.Cmd)
+		}
+		add("CPU / MEM", fmt.Sprintf("%.1f%% / %.1f%%", a.CPU, a.PMem))
+	}
+	if a.Metric != "" {
+		add("Value", fmt.Sprintf("%s = %.1f (threshold %g)", a.Metric, a.Value, a.Threshold))
+	} else if a.Expr != "" {
+		add("Condition", a.Expr)
+	}
+	add("Host", a.Host)
+	add("Time", a.Time.Format(time.RFC3339))
+	return out
+}
+
+var emailHTML = template.Must(template.New("email").Funcs(template.FuncMap{
+	"details": alertDetails,
+}).Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+{{range .}}
+<h3 style="color: {{if .Resolved}}#2e7d32{{else}}#c62828{{end}}">{{.Text}}</h3>
+<table cellpadding="4" style="border-collapse: collapse">
+{{range details .}}<tr><th align="left">{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+{{end}}
+</body></html>
+`))
+
+func messageID(from string) string {
+	domain := "sentinel"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	var b [12]byte
+	rand.Read(b[:])
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+}
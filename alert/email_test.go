@@ -0,0 +1,349 @@
+package alert
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"sentinel/config"
+)
+
+// smtpSink is a minimal in-process SMTP server that records the messages
+// it accepts.
+type smtpSink struct {
+	ln         net.Listener
+	tls        *tls.Config // STARTTLS is offered if not nil
+	rejectRcpt bool        // answer RCPT with 550
+
+	mu   sync.Mutex
+	msgs []sinkMessage
+}
+
+type sinkMessage struct {
+	from string
+	to   []string
+	data []byte
+	tls  bool   // sent after STARTTLS
+	auth string // decoded AUTH PLAIN credentials
+}
+
+func newSMTPSink(t *testing.T, tlsConfig *tls.Config) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, tls: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) messages() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.msgs
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ESMTP")
+
+	var msg sinkMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-sink")
+			if s.tls != nil && !msg.tls {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			tc := tls.Server(conn, s.tls)
+			if tc.Handshake() != nil {
+				return
+			}
+			conn, tp = tc, textproto.NewConn(tc)
+			msg.tls = true
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			msg.auth = string(creds)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			msg.from = strings.TrimPrefix(arg, "FROM:")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			if s.rejectRcpt {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			msg.to = append(msg.to, strings.TrimPrefix(arg, "TO:"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			msg.data, err = tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func newTestEmail(t *testing.T, settings map[string]any) *emailNotifier {
+	t.Helper()
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(config.Channel{Name: "mail", Type: "email", Settings: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.(*emailNotifier)
+}
+
+// sinkTLS returns a server config and the pool trusting it, using the
+// certificate of an httptest TLS server, valid for 127.0.0.1.
+func sinkTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	srv := httptest.NewTLSServer(nil)
+	t.Cleanup(srv.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	return &tls.Config{Certificates: srv.TLS.Certificates}, roots
+}
+
+// emailTestAlerts returns a firing alert with markup to escape and a
+// resolved system alert.
+func emailTestAlerts() []Alert {
+	a := testAlert(0)
+	a.Severity = "critical"
+	a.Text = "<script>alert(1)</script> uses 95% CPU"
+	a.Cmd = "/bin/evil --x=\"a&b\""
+	a.Threshold = 80
+
+	r := testAlert(1)
+	r.Rule, r.Pid, r.Resolved = "swap", 0, true
+	r.Text, r.Expr = "Swap usage back to normal", "swap > 50"
+	return []Alert{a, r}
+}
+
+func TestEmailNoTLS(t *testing.T) {
+	sink := newSMTPSink(t, nil)
+	n := newTestEmail(t, map[string]any{
+		"host": "127.0.0.1",
+		"port": sink.port(),
+		"tls":  "none",
+		"from": "Sentinel <sentinel@example.com>",
+		"to":   []string{"ops@example.com", "Dev Team <dev@example.com>"},
+	})
+
+	if err := n.Notify(context.Background(), emailTestAlerts()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sink.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("%d messages, want all alerts in one", len(msgs))
+	}
+	m := msgs[0]
+	if m.tls || m.from != "<sentinel@example.com>" ||
+		strings.Join(m.to, " ") != "<ops@example.com> <dev@example.com>" {
+		t.Errorf("envelope tls=%v from %s to %v", m.tls, m.from, m.to)
+	}
+
+	hdr, text, html := parseAlertMail(t, m.data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(hdr.Get("Subject"))
+	if err != nil || subject != "[sentinel] 2 alerts (1 firing, 1 resolved) on box" {
+		t.Errorf("subject %q (%v)", subject, err)
+	}
+	if hdr.Get("MIME-Version") != "1.0" || !strings.HasSuffix(hdr.Get("Message-ID"), "@example.com>") {
+		t.Errorf("headers %v", hdr)
+	}
+
+	for _, want := range []string{
+		"<script>alert(1)</script> uses 95% CPU\n",
+		"  Command:   /bin/evil --x=\"a&b\"\n",
+		"  Value:     cpu = 95.0 (threshold 80)\n",
+		"Swap usage back to normal\n  Rule:      swap (warning)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part lacks %q:\n%s", want, text)
+		}
+	}
+
+	if strings.Contains(html, "<script>") {
+		t.Errorf("HTML part not escaped:\n%s", html)
+	}
+	for _, want := range []string{
+		"&lt;script&gt;alert(1)&lt;/script&gt; uses 95% CPU</h3>",
+		"/bin/evil --x=&#34;a&amp;b&#34;",
+		`<h3 style="color: #c62828">`,
+		`<h3 style="color: #2e7d32">Swap usage back to normal</h3>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML part lacks %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestEmailStartTLS(t *testing.T) {
+	serverTLS, roots := sinkTLS(t)
+	sink := newSMTPSink(t, serverTLS)
+	n := newTestEmail(t, map[string]any{
+		"host":     "127.0.0.1",
+		"port":     sink.port(),
+		"username": "bot",
+		"password": "pw",
+		"from":     "sentinel@example.com",
+		"to":       []string{"ops@example.com"},
+	})
+	n.roots = roots
+
+	if err := n.Notify(context.Background(), emailTestAlerts()[:1]); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sink.messages()
+	if len(msgs) != 1 || !msgs[0].tls {
+		t.Fatalf("messages %+v, want one sent over STARTTLS", msgs)
+	}
+	if msgs[0].auth != "\x00bot\x00pw" {
+		t.Errorf("auth %q", msgs[0].auth)
+	}
+
+	hdr, _, _ := parseAlertMail(t, msgs[0].data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(hdr.Get("Subject"))
+	if subject != "[sentinel] <script>alert(1)</script> uses 95% CPU on box" {
+		t.Errorf("subject %q", subject)
+	}
+}
+
+func TestEmailErrors(t *testing.T) {
+	settings := func(port int) map[string]any {
+		return map[string]any{"host": "127.0.0.1", "port": port, "from": "s@example.com", "to": []string{"o@example.com"}}
+	}
+
+	// STARTTLS is required by default
+	plain := newSMTPSink(t, nil)
+	err := newTestEmail(t, settings(plain.port())).Notify(context.Background(), emailTestAlerts())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") || !IsPermanent(err) {
+		t.Errorf("server without STARTTLS: %v", err)
+	}
+
+	rejecting := newSMTPSink(t, nil)
+	rejecting.rejectRcpt = true
+	s := settings(rejecting.port())
+	s["tls"] = "none"
+	err = newTestEmail(t, s).Notify(context.Background(), emailTestAlerts())
+	if err == nil || !strings.Contains(err.Error(), "o@example.com") || !IsPermanent(err) {
+		t.Errorf("rejected recipient: %v", err)
+	}
+
+	// Nothing listening: worth a retry
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	err = newTestEmail(t, settings(port)).Notify(context.Background(), emailTestAlerts())
+	if err == nil || IsPermanent(err) {
+		t.Errorf("connection refused: %v", err)
+	}
+
+	for _, bad := range []map[string]any{
+		{"from": "s@example.com", "to": []string{"o@example.com"}},
+		{"host": "h", "from": "not an address", "to": []string{"o@example.com"}},
+		{"host": "h", "from": "s@example.com"},
+		{"host": "h", "from": "s@example.com", "to": []string{"o@example.com"}, "tls": "ssl"},
+	} {
+		raw, _ := json.Marshal(bad)
+		if _, err := New(config.Channel{Name: "mail", Type: "email", Settings: raw}); err == nil {
+			t.Errorf("settings %s accepted", raw)
+		}
+	}
+}
+
+// parseAlertMail checks that data is a multipart/alternative message with
+// a plain-text and an HTML part and returns its header and decoded parts.
+func parseAlertMail(t *testing.T, data []byte) (hdr mail.Header, text, html string) {
+	t.Helper()
+	m, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q (%v)", m.Header.Get("Content-Type"), err)
+	}
+
+	var types []string
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The reader decodes quoted-printable and drops the header
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct := p.Header.Get("Content-Type")
+		types = append(types, ct)
+		switch ct {
+		case "text/plain; charset=utf-8":
+			text = string(body)
+		case "text/html; charset=utf-8":
+			html = string(body)
+		}
+	}
+	if got := strings.Join(types, ", "); got != "text/plain; charset=utf-8, text/html; charset=utf-8" {
+		t.Fatalf("parts %s, want plain text then HTML", got)
+	}
+	if n := bytes.Count(data, []byte("Content-Transfer-Encoding: quoted-printable")); n != 2 {
+		t.Errorf("%d quoted-printable parts, want 2", n)
+	}
+	return m.Header, text, html
+}
@@ -0,0 +1,80 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testAlert returns a firing alert of rule "hot" on process 100+i, with
+// markup in its process name and command line.
+func testAlert(i int) Alert {
+	return Alert{
+		ID:       fmt.Sprintf("a%d", i),
+		Rule:     "hot",
+		Severity: "warning",
+		Time:     time.Unix(1_700_000_000, 0).UTC(),
+		Host:     "box",
+		Title:    "High CPU",
+		Text:     fmt.Sprintf("worker %d uses 95%% CPU", i),
+		Pid:      100 + i,
+		Comm:     "a<b>&c",
+		Cmd:      "/bin/worker --flag <x>",
+		User:     "root",
+		Metric:   "cpu",
+		Value:    95,
+	}
+}
+
+// testAlerts returns n alerts built by testAlert, with IDs a0 to a<n-1>.
+func testAlerts(n int) []Alert {
+	out := make([]Alert, n)
+	for i := range out {
+		out[i] = testAlert(i)
+	}
+	return out
+}
+
+type recordedRequest struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// decode unmarshals the JSON body of the request into v.
+func (r recordedRequest) decode(t *testing.T, v any) {
+	t.Helper()
+	if ct := r.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type %q", ct)
+	}
+	if err := json.Unmarshal([]byte(r.body), v); err != nil {
+		t.Fatalf("invalid payload %s: %v", r.body, err)
+	}
+}
+
+// recordingServer records the requests it receives and answers them with
+// the statuses given, in turn, then with 200. Error answers have the body
+// "invalid_payload".
+func recordingServer(t *testing.T, statuses ...int) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var got []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, recordedRequest{r.Method, r.Header, string(body)})
+
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "invalid_payload\n")
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
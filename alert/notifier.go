@@ -0,0 +1,201 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sentinel/config"
+)
+
+// Alert is one notification: a rule that started firing or resolved.
+type Alert struct {
+	ID       string          `json:"id"`
+	Rule     string          `json:"rule"`
+	Severity config.Severity `json:"severity"`
+	Resolved bool            `json:"resolved"`
+	Time     time.Time       `json:"time"`
+	Host     string          `json:"host"`
+
+	// Title is a one-line summary, Text the full plain-text message
+	Title string `json:"title"`
+	Text  string `json:"text"`
+
+	// Process the alert refers to; Pid is 0 for system-wide alerts
+	Pid  int    `json:"pid,omitempty"`
+	Comm string `json:"comm,omitempty"`
+	Cmd  string `json:"cmd,omitempty"`
+	User string `json:"user,omitempty"`
+
+	CPU   float64 `json:"cpu"`
+	PMem  float64 `json:"mem"`
+	RSSKB int64   `json:"rss_kb"`
+
+	Expr string `json:"expr"` // condition of the rule
+
+	// Metric compared against Threshold, when the condition is a single
+	// comparison such as "cpu >= 80"
+	Metric    string  `json:"metric,omitempty"`
+	Value     float64 `json:"value,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+
+	Duration time.Duration `json:"duration_ns"`      // how long it held (firing) or fired (resolved)
+	Exited   bool          `json:"exited,omitempty"` // resolved because the process exited
+}
+
+// NewID returns a short unique alert ID that sorts by time.
+func NewID(now time.Time) string {
+	return fmt.Sprintf("%x-%04x", now.Unix(), rand.Uint32()&0xffff)
+}
+
+// State returns "firing" or "resolved".
+func (a Alert) State() string {
+	if a.Resolved {
+		return "resolved"
+	}
+	return "firing"
+}
+
+// Notifier delivers alerts to one channel. Alerts raised in the same daemon
+// tick are passed together so channels can batch them.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// PartialError is returned by a notifier that sends a batch as several
+// messages when the first Sent alerts were delivered before Err, so that a
+// retry can leave them out.
+type PartialError struct {
+	Sent int
+	Err  error
+}
+
+func (e *PartialError) Error() string { return e.Err.Error() }
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// partial wraps err in a PartialError if any alerts were sent.
+func partial(sent int, err error) error {
+	if sent == 0 {
+		return err
+	}
+	return &PartialError{Sent: sent, Err: err}
+}
+
+// permanentError is a delivery error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a rejected payload or a
+// channel that no longer exists.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// statusError returns the error for a non-2xx HTTP answer, with the reason
+// given in its body. Client errors are permanent, except 408 Request Timeout
+// and 429 Too Many Requests.
+func statusError(service string, resp *http.Response, reason []byte) error {
+	err := fmt.Errorf("%s: %s: %s", service, resp.Status, strings.TrimSpace(string(reason)))
+	code := resp.StatusCode
+	if code/100 == 4 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+// Factory builds a notifier for a configured channel from its settings.
+type Factory func(name string, settings json.RawMessage) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a channel type available to config. It panics if the type
+// is registered twice.
+func Register(typ string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[typ]; dup {
+		panic("alert: channel type registered twice: " + typ)
+	}
+	registry[typ] = f
+}
+
+// Types returns the registered channel types, sorted.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	out := make([]string, 0, len(registry))
+	for typ := range registry {
+		out = append(out, typ)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// New builds the notifier for a configured channel.
+func New(ch config.Channel) (Notifier, error) {
+	if ch.Name == "" {
+		return nil, fmt.Errorf("channel has no name")
+	}
+
+	registryMu.RLock()
+	f, ok := registry[ch.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("channel %q: unknown type %q (known: %v)", ch.Name, ch.Type, Types())
+	}
+
+	n, err := f(ch.Name, ch.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("channel %q: %w", ch.Name, err)
+	}
+	return n, nil
+}
+
+// FromWebhook builds the notifier for a webhook entry of the config, using
+// the channel type given by its kind.
+func FromWebhook(name string, wh config.Webhook) (Notifier, error) {
+	settings, err := json.Marshal(map[string]string{"url": wh.URL})
+	if err != nil {
+		return nil, err
+	}
+	return New(config.Channel{Name: name, Type: wh.Kind(), Settings: settings})
+}
+
+// decodeSettings unmarshals channel settings, rejecting unknown fields so
+// typos in the config are reported.
+func decodeSettings(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Retry policy of the outbox. The delay doubles per failed attempt up to
+// outboxMaxDelay; entries older than outboxMaxAge, or that failed with a
+// permanent error, are moved to failed/.
+const (
+	outboxBaseDelay = 5 * time.Second
+	outboxMaxDelay  = 15 * time.Minute
+	outboxMaxAge    = 24 * time.Hour
+
+	// outboxSendTimeout bounds one delivery attempt.
+	outboxSendTimeout = 30 * time.Second
+)
+
+// Entries kept in failed/ and corrupt/: the newest outboxKeepCount, for at
+// most outboxKeepAge.
+const (
+	outboxKeepCount = 500
+	outboxKeepAge   = 7 * 24 * time.Hour
+)
+
+// OutboxEntry is one batch of alerts waiting to be delivered to a channel.
+// Each entry is stored as its own JSON file so a crash can only lose the
+// entry being written.
+type OutboxEntry struct {
+	ID          string    `json:"id"`
+	Channel     string    `json:"channel"`
+	Alerts      []Alert   `json:"alerts"`
+	Created     time.Time `json:"created"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"` // zero once moved to failed/
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Outbox is a persistent delivery queue. Enqueue writes entries to disk and
+// Run delivers them in the background, retrying failures with exponential
+// backoff and jitter. Pending entries survive restarts. Files that cannot
+// be read as entries are moved to corrupt/.
+type Outbox struct {
+	dir    string
+	wake   chan struct{}
+	logger *slog.Logger
+
+	mu sync.Mutex // serializes file updates between Enqueue and Run
+}
+
+// OpenOutbox opens (creating if needed) the outbox stored in dir. Problems
+// with its files are logged to logger, if not nil.
+func OpenOutbox(dir string, logger *slog.Logger) (*Outbox, error) {
+	for _, sub := range []string{"failed", "corrupt"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Outbox{dir: dir, wake: make(chan struct{}, 1), logger: logger}, nil
+}
+
+// OutboxDir returns the default outbox location under the sentinel state dir.
+func OutboxDir(stateDir string) string {
+	return filepath.Join(stateDir, "outbox")
+}
+
+// Enqueue stores one batch per channel and wakes the delivery worker.
+func (o *Outbox) Enqueue(batches map[string][]Alert, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var errs []error
+	for channel, alerts := range batches {
+		e := &OutboxEntry{
+			ID:          newEntryID(now),
+			Channel:     channel,
+			Alerts:      alerts,
+			Created:     now,
+			NextAttempt: now,
+		}
+		if err := writeJSONFile(o.entryPath(e.ID), e); err != nil {
+			errs = append(errs, fmt.Errorf("outbox: %s: %w", channel, err))
+		}
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return errors.Join(errs...)
+}
+
+// Run delivers due entries until ctx is done. lookup resolves a channel name
+// to its current notifier; it is called for every attempt so that config
+// reloads take effect. report is called after every attempt with a nil error
+// on success; after a failure the entry's NextAttempt is zero when it has
+// been given up on.
+func (o *Outbox) Run(ctx context.Context, lookup func(channel string) (Notifier, bool), report func(e *OutboxEntry, err error)) {
+	o.prune(time.Now())
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		case <-timer.C:
+		}
+
+		next := o.deliverDue(ctx, lookup, report)
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// deliverDue attempts every due entry and returns when the next one is due.
+func (o *Outbox) deliverDue(ctx context.Context, lookup func(string) (Notifier, bool), report func(*OutboxEntry, error)) time.Time {
+	next := time.Now().Add(outboxMaxDelay)
+
+	entries, corrupt, err := readEntries(o.dir)
+	if err != nil {
+		o.logger.Error("outbox unreadable", "dir", o.dir, "err", err)
+	}
+	for name, err := range corrupt {
+		o.quarantine(name, err)
+	}
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return next
+		}
+
+		now := time.Now()
+		if e.NextAttempt.After(now) {
+			if e.NextAttempt.Before(next) {
+				next = e.NextAttempt
+			}
+			continue
+		}
+
+		err := o.attempt(ctx, e, lookup)
+		if err == nil {
+			e.Attempts++
+			o.remove(e)
+			if report != nil {
+				report(e, nil)
+			}
+			continue
+		}
+
+		// Keep only the alerts that were not delivered, so that retries do
+		// not send the others again
+		var p *PartialError
+		if errors.As(err, &p) && p.Sent > 0 && p.Sent < len(e.Alerts) {
+			done := *e
+			done.Alerts = e.Alerts[:p.Sent]
+			done.Attempts++
+			if report != nil {
+				report(&done, nil)
+			}
+			e.Alerts = e.Alerts[p.Sent:]
+		}
+		if ctx.Err() != nil {
+			// Shutting down: keep the entry for the next start
+			o.update(e)
+			return next
+		}
+
+		e.Attempts++
+		e.LastError = err.Error()
+		givenUp := IsPermanent(err) || time.Since(e.Created) > outboxMaxAge
+		if givenUp {
+			e.NextAttempt = time.Time{}
+		} else {
+			e.NextAttempt = time.Now().Add(backoff(e.Attempts))
+		}
+		if report != nil {
+			report(e, err)
+		}
+
+		if givenUp {
+			o.fail(e)
+			continue
+		}
+		o.update(e)
+		if e.NextAttempt.Before(next) {
+			next = e.NextAttempt
+		}
+	}
+	return next
+}
+
+func (o *Outbox) attempt(ctx context.Context, e *OutboxEntry, lookup func(string) (Notifier, bool)) error {
+	n, ok := lookup(e.Channel)
+	if !ok {
+		return Permanent(fmt.Errorf("channel %q is not configured", e.Channel))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	defer cancel()
+	return n.Notify(ctx, e.Alerts)
+}
+
+// backoff returns the delay before the given retry: exponential with "equal
+// jitter", i.e. uniformly distributed in [d/2, d].
+func backoff(attempts int) time.Duration {
+	d := outboxBaseDelay
+	for i := 1; i < attempts && d < outboxMaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, outboxMaxDelay)
+	return d/2 + rand.N(d/2+1)
+}
+
+// Pending returns the queued entries, oldest first. Unreadable files are
+// skipped.
+func (o *Outbox) Pending() ([]*OutboxEntry, error) {
+	entries, _, err := readEntries(o.dir)
+	return entries, err
+}
+
+// Failed returns the entries that were given up on, oldest first.
+func (o *Outbox) Failed() ([]*OutboxEntry, error) {
+	entries, _, err := readEntries(filepath.Join(o.dir, "failed"))
+	return entries, err
+}
+
+func (o *Outbox) update(e *OutboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := writeJSONFile(o.entryPath(e.ID), e); err != nil {
+		o.logger.Error("failed to update outbox entry", "id", e.ID, "channel", e.Channel, "err", err)
+	}
+}
+
+func (o *Outbox) remove(e *OutboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := os.Remove(o.entryPath(e.ID)); err != nil {
+		// Left behind, the entry would be delivered again
+		o.logger.Error("failed to remove delivered outbox entry", "id", e.ID, "channel", e.Channel, "err", err)
+	}
+}
+
+// fail moves an entry to failed/, where it stays for inspection.
+func (o *Outbox) fail(e *OutboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := writeJSONFile(filepath.Join(o.dir, "failed", e.ID+".json"), e)
+	if err == nil {
+		err = os.Remove(o.entryPath(e.ID))
+	}
+	if err != nil {
+		o.logger.Error("failed to move outbox entry to failed/", "id", e.ID, "channel", e.Channel, "err", err)
+	}
+	o.pruneDir(filepath.Join(o.dir, "failed"), time.Now())
+}
+
+// quarantine moves a file that is not a valid entry to corrupt/, rather
+// than skip it on every pass.
+func (o *Outbox) quarantine(name string, cause error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	dst := filepath.Join(o.dir, "corrupt", name)
+	if err := os.Rename(filepath.Join(o.dir, name), dst); err != nil {
+		o.logger.Error("unreadable outbox entry", "file", name, "err", cause, "move_err", err)
+		return
+	}
+	o.logger.Error("unreadable outbox entry moved to corrupt/", "file", name, "err", cause)
+	o.pruneDir(filepath.Join(o.dir, "corrupt"), time.Now())
+}
+
+// prune applies the retention limits to failed/ and corrupt/.
+func (o *Outbox) prune(now time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pruneDir(filepath.Join(o.dir, "failed"), now)
+	o.pruneDir(filepath.Join(o.dir, "corrupt"), now)
+}
+
+// pruneDir removes the files of dir older than outboxKeepAge and the oldest
+// ones beyond outboxKeepCount. o.mu must be held.
+func (o *Outbox) pruneDir(dir string, now time.Time) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		o.logger.Warn("failed to prune outbox", "dir", dir, "err", err)
+		return
+	}
+
+	// Entry names sort by creation time, ReadDir returns them sorted
+	for i, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		if len(files)-i <= outboxKeepCount && now.Sub(info.ModTime()) <= outboxKeepAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			o.logger.Warn("failed to prune outbox", "file", f.Name(), "err", err)
+		}
+	}
+}
+
+func (o *Outbox) entryPath(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
+
+// newEntryID returns an ID that sorts by creation time.
+func newEntryID(now time.Time) string {
+	return fmt.Sprintf("%020d-%08x", now.UnixNano(), rand.Uint32())
+}
+
+// readEntries reads the entries stored in dir, oldest first. Files that
+// cannot be read or decoded are returned in corrupt with their error.
+func readEntries(dir string) (entries []*OutboxEntry, corrupt map[string]error, err error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	corrupt = make(map[string]error)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed since the listing
+			continue
+		}
+		if err != nil {
+			corrupt[f.Name()] = err
+			continue
+		}
+		var e OutboxEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			corrupt[f.Name()] = err
+			continue
+		}
+		if e.ID+".json" != f.Name() || len(e.Alerts) == 0 {
+			corrupt[f.Name()] = fmt.Errorf("not an outbox entry")
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, corrupt, nil
+}
+
+// writeJSONFile writes v atomically: to a temp file first, then renamed.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
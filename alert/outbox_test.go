@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeNotifier delivers the first ok alerts of each call and fails with err
+// after them.
+type fakeNotifier struct {
+	ok   int
+	err  error
+	sent []string // IDs of the delivered alerts
+}
+
+func (n *fakeNotifier) Name() string { return "fake" }
+
+func (n *fakeNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	for i, a := range alerts {
+		if i == n.ok && n.err != nil {
+			return partial(i, n.err)
+		}
+		n.sent = append(n.sent, a.ID)
+	}
+	return nil
+}
+
+func openTestOutbox(t *testing.T) *Outbox {
+	t.Helper()
+	o, err := OpenOutbox(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func lookupFake(n Notifier) func(string) (Notifier, bool) {
+	return func(name string) (Notifier, bool) {
+		return n, name == "fake"
+	}
+}
+
+func TestOutboxPartialDelivery(t *testing.T) {
+	o := openTestOutbox(t)
+	n := &fakeNotifier{ok: 2, err: errors.New("slack: 503 Service Unavailable")}
+	if err := o.Enqueue(map[string][]Alert{"fake": testAlerts(3)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []string
+	report := func(e *OutboxEntry, err error) {
+		result := "ok"
+		if err != nil {
+			result = "err"
+		}
+		for _, a := range e.Alerts {
+			reports = append(reports, a.ID+"="+result)
+		}
+	}
+	o.deliverDue(context.Background(), lookupFake(n), report)
+
+	pending, _ := o.Pending()
+	if len(pending) != 1 || len(pending[0].Alerts) != 1 || pending[0].Alerts[0].ID != "a2" {
+		t.Fatalf("pending = %+v, want only alert a2 left", pending)
+	}
+	if got, want := reports, []string{"a0=ok", "a1=ok", "a2=err"}; !slices.Equal(got, want) {
+		t.Errorf("reports = %v, want %v", got, want)
+	}
+
+	// The retry only sends what was left
+	n.err = nil
+	pending[0].NextAttempt = time.Now()
+	o.update(pending[0])
+	o.deliverDue(context.Background(), lookupFake(n), nil)
+	if got, want := n.sent, []string{"a0", "a1", "a2"}; !slices.Equal(got, want) {
+		t.Errorf("sent = %v, want %v", got, want)
+	}
+	if pending, _ := o.Pending(); len(pending) != 0 {
+		t.Errorf("still pending: %+v", pending)
+	}
+}
+
+func TestOutboxPermanentError(t *testing.T) {
+	tests := []struct {
+		name   string
+		lookup func(string) (Notifier, bool)
+	}{
+		{"rejected", lookupFake(&fakeNotifier{err: Permanent(errors.New("webhook: 400 Bad Request"))})},
+		{"unknown channel", func(string) (Notifier, bool) { return nil, false }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := openTestOutbox(t)
+			o.Enqueue(map[string][]Alert{"fake": testAlerts(1)}, time.Now())
+
+			var gaveUp bool
+			o.deliverDue(context.Background(), tt.lookup, func(e *OutboxEntry, err error) {
+				gaveUp = err != nil && e.NextAttempt.IsZero()
+			})
+			if !gaveUp {
+				t.Error("not reported as given up")
+			}
+			pending, _ := o.Pending()
+			failed, _ := o.Failed()
+			if len(pending) != 0 || len(failed) != 1 {
+				t.Errorf("%d pending, %d failed, want 0 and 1", len(pending), len(failed))
+			}
+		})
+	}
+}
+
+func TestOutboxTransientError(t *testing.T) {
+	o := openTestOutbox(t)
+	o.Enqueue(map[string][]Alert{"fake": testAlerts(1)}, time.Now())
+	o.deliverDue(context.Background(), lookupFake(&fakeNotifier{err: errors.New("timeout")}), nil)
+
+	pending, _ := o.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 1 || !pending[0].NextAttempt.After(time.Now()) {
+		t.Fatalf("pending = %+v, want one entry scheduled for a retry", pending)
+	}
+}
+
+func TestOutboxCorruptEntry(t *testing.T) {
+	o := openTestOutbox(t)
+	if err := os.WriteFile(filepath.Join(o.dir, "bad.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o.deliverDue(context.Background(), lookupFake(&fakeNotifier{}), nil)
+
+	if _, err := os.Stat(filepath.Join(o.dir, "corrupt", "bad.json")); err != nil {
+		t.Errorf("corrupt entry not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(o.dir, "bad.json")); !os.IsNotExist(err) {
+		t.Errorf("corrupt entry still pending")
+	}
+}
+
+func TestOutboxPrune(t *testing.T) {
+	o := openTestOutbox(t)
+	failed := filepath.Join(o.dir, "failed")
+	now := time.Now()
+
+	old := filepath.Join(failed, "0-old.json")
+	os.WriteFile(old, []byte("{}"), 0o600)
+	os.Chtimes(old, now.Add(-outboxKeepAge-time.Hour), now.Add(-outboxKeepAge-time.Hour))
+	for i := range outboxKeepCount + 1 {
+		os.WriteFile(filepath.Join(failed, newEntryID(now.Add(time.Duration(i)))+".json"), []byte("{}"), 0o600)
+	}
+
+	o.prune(now)
+	files, _ := os.ReadDir(failed)
+	if len(files) != outboxKeepCount {
+		t.Errorf("%d files kept, want %d", len(files), outboxKeepCount)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired entry kept")
+	}
+}
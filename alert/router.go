@@ -0,0 +1,166 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"sentinel/config"
+)
+
+// Router delivers alerts to the channels selected by the configured routes.
+type Router struct {
+	channels map[string]Notifier
+	order    []string // channel names in config order
+	routes   []config.Route
+	fallback string // channel of alerts no route matches, "" for none
+}
+
+// NewRouter builds the notifiers and routes of cfg. The active webhook of
+// the TUI is added as a channel named after it unless a configured
+// channel already uses that name, and receives the alerts no route matches.
+// Invalid channels and routes that reference unknown channels are skipped
+// and reported in errs.
+func NewRouter(cfg *config.SentinelConfig) (*Router, []error) {
+	r := &Router{channels: make(map[string]Notifier)}
+	var errs []error
+
+	for _, ch := range cfg.Channels {
+		if _, dup := r.channels[ch.Name]; dup {
+			errs = append(errs, fmt.Errorf("channel %q: duplicate name", ch.Name))
+			continue
+		}
+		n, err := New(ch)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.add(n)
+	}
+
+	if wh := cfg.Webhooks[cfg.ActiveWebhook]; wh.URL != "" {
+		if _, dup := r.channels[cfg.ActiveWebhook]; !dup {
+			n, err := FromWebhook(cfg.ActiveWebhook, wh)
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				r.add(n)
+			}
+		}
+	}
+	if _, ok := r.channels[cfg.ActiveWebhook]; ok {
+		r.fallback = cfg.ActiveWebhook
+	}
+
+	for i, rt := range cfg.Routes {
+		if err := r.checkRoute(rt); err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %w", i+1, err))
+			continue
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, errs
+}
+
+func (r *Router) add(n Notifier) {
+	r.channels[n.Name()] = n
+	r.order = append(r.order, n.Name())
+}
+
+func (r *Router) checkRoute(rt config.Route) error {
+	if len(rt.Channels) == 0 {
+		return fmt.Errorf("no channels")
+	}
+	for _, name := range rt.Channels {
+		if _, ok := r.channels[name]; !ok {
+			return fmt.Errorf("unknown channel %q", name)
+		}
+	}
+	for _, s := range rt.Severity {
+		if !s.Valid() {
+			return fmt.Errorf("unknown severity %q", s)
+		}
+	}
+	return nil
+}
+
+// Channels returns the names of the configured channels.
+func (r *Router) Channels() []string {
+	return r.order
+}
+
+// Route returns the channels an alert should be sent to. Without routes
+// that is every channel; an alert no route matches goes to the active
+// webhook, if any.
+func (r *Router) Route(a Alert) []string {
+	if len(r.routes) == 0 {
+		return r.order
+	}
+
+	selected := make(map[string]bool)
+	for _, rt := range r.routes {
+		if !matchRoute(rt, a) {
+			continue
+		}
+		for _, name := range rt.Channels {
+			selected[name] = true
+		}
+	}
+
+	if len(selected) == 0 && r.fallback != "" {
+		return []string{r.fallback}
+	}
+
+	out := make([]string, 0, len(selected))
+	for _, name := range r.order {
+		if selected[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func matchRoute(rt config.Route, a Alert) bool {
+	if len(rt.Rules) > 0 && !slices.Contains(rt.Rules, a.Rule) {
+		return false
+	}
+	if len(rt.Severity) > 0 && !slices.Contains(rt.Severity, a.Severity) {
+		return false
+	}
+	return true
+}
+
+// Notifier returns the notifier of a channel.
+func (r *Router) Notifier(name string) (Notifier, bool) {
+	n, ok := r.channels[name]
+	return n, ok
+}
+
+// Group splits alerts into one batch per destination channel.
+func (r *Router) Group(alerts []Alert) map[string][]Alert {
+	batches := make(map[string][]Alert)
+	for _, a := range alerts {
+		for _, name := range r.Route(a) {
+			batches[name] = append(batches[name], a)
+		}
+	}
+	return batches
+}
+
+// Dispatch groups alerts by channel and notifies each channel once. Errors
+// are returned per channel name.
+func (r *Router) Dispatch(ctx context.Context, alerts []Alert) map[string]error {
+	batches := r.Group(alerts)
+
+	errs := make(map[string]error)
+	for _, name := range r.order {
+		batch := batches[name]
+		if len(batch) == 0 {
+			continue
+		}
+		if err := r.channels[name].Notify(ctx, batch); err != nil {
+			errs[name] = err
+		}
+	}
+	return errs
+}
@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"sentinel/config"
+)
+
+// routerConfig returns a config with a Discord channel for each name.
+func routerConfig(names ...string) *config.SentinelConfig {
+	cfg := &config.SentinelConfig{}
+	for _, name := range names {
+		settings, _ := json.Marshal(map[string]string{"url": "http://127.0.0.1:1/" + name})
+		cfg.Channels = append(cfg.Channels, config.Channel{Name: name, Type: "discord", Settings: settings})
+	}
+	return cfg
+}
+
+func mustRouter(t *testing.T, cfg *config.SentinelConfig) *Router {
+	t.Helper()
+	r, errs := NewRouter(cfg)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return r
+}
+
+func TestRouterRoute(t *testing.T) {
+	cfg := routerConfig("oncall", "team", "db")
+	cfg.Routes = []config.Route{
+		{Severity: []config.Severity{config.SeverityCritical}, Channels: []string{"oncall"}},
+		{Severity: []config.Severity{config.SeverityWarning, config.SeverityInfo}, Channels: []string{"team"}},
+		{Rules: []string{"pg-slow"}, Channels: []string{"db", "oncall"}},
+	}
+	r := mustRouter(t, cfg)
+
+	tests := []struct {
+		rule     string
+		severity config.Severity
+		want     string
+	}{
+		{"hot", config.SeverityCritical, "oncall"},
+		{"hot", config.SeverityWarning, "team"},
+		{"hot", config.SeverityInfo, "team"},
+		{"pg-slow", config.SeverityWarning, "oncall team db"}, // union in config order
+		{"pg-slow", config.SeverityCritical, "oncall db"},     // oncall only once
+		{"hot", "page", ""},                                   // no route, no fallback
+	}
+	for _, tt := range tests {
+		got := r.Route(Alert{Rule: tt.rule, Severity: tt.severity})
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s/%s: channels %v, want %q", tt.rule, tt.severity, got, tt.want)
+		}
+	}
+
+	// Without routes every channel gets every alert
+	r = mustRouter(t, routerConfig("oncall", "team"))
+	if got := r.Route(Alert{Rule: "hot"}); !slices.Equal(got, []string{"oncall", "team"}) {
+		t.Errorf("no routes: channels %v", got)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	cfg := routerConfig("oncall")
+	cfg.Webhooks = map[string]config.Webhook{"tui": {URL: "http://127.0.0.1:1/tui"}}
+	cfg.ActiveWebhook = "tui"
+	cfg.Routes = []config.Route{{Severity: []config.Severity{config.SeverityCritical}, Channels: []string{"oncall"}}}
+	r := mustRouter(t, cfg)
+
+	if got := r.Channels(); !slices.Equal(got, []string{"oncall", "tui"}) {
+		t.Errorf("channels %v, want the active webhook added", got)
+	}
+	if got := r.Route(Alert{Rule: "hot", Severity: config.SeverityWarning}); !slices.Equal(got, []string{"tui"}) {
+		t.Errorf("unrouted alert: channels %v, want the active webhook", got)
+	}
+	if got := r.Route(Alert{Rule: "hot", Severity: config.SeverityCritical}); !slices.Equal(got, []string{"oncall"}) {
+		t.Errorf("routed alert: channels %v, want only its route", got)
+	}
+
+	// A configured channel named like the active webhook takes its place
+	cfg = routerConfig("tui")
+	cfg.Webhooks = map[string]config.Webhook{"tui": {URL: "http://127.0.0.1:1/other"}}
+	cfg.ActiveWebhook = "tui"
+	r = mustRouter(t, cfg)
+	if got := r.Channels(); !slices.Equal(got, []string{"tui"}) {
+		t.Errorf("channels %v, want one tui channel", got)
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	cfg := routerConfig("oncall", "oncall")
+	cfg.Routes = []config.Route{
+		{Channels: []string{"pager"}},
+		{Channels: []string{"oncall"}, Severity: []config.Severity{"loud"}},
+		{Rules: []string{"hot"}},
+		{Channels: []string{"oncall"}},
+	}
+	r, errs := NewRouter(cfg)
+
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	want := []string{
+		`channel "oncall": duplicate name`,
+		`route 1: unknown channel "pager"`,
+		`route 2: unknown severity "loud"`,
+		`route 3: no channels`,
+	}
+	if !slices.Equal(msgs, want) {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(msgs, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The valid route is kept, so the alert is not sent everywhere
+	if got := r.Route(Alert{Rule: "hot"}); !slices.Equal(got, []string{"oncall"}) {
+		t.Errorf("channels %v", got)
+	}
+}
+
+func TestRouterDispatch(t *testing.T) {
+	srv, reqs := recordingServer(t)
+	cfg := routerConfig("team")
+	cfg.Channels[0].Settings, _ = json.Marshal(map[string]string{"url": srv.URL})
+	cfg.Routes = []config.Route{{Rules: []string{"hot"}, Channels: []string{"team"}}}
+	r := mustRouter(t, cfg)
+
+	alerts := testAlerts(2)
+	alerts[1].Rule = "cold"
+	if errs := r.Dispatch(context.Background(), alerts); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	got := discordPayloads(t, *reqs)
+	if len(got) != 1 || len(got[0].Embeds) != 1 {
+		t.Errorf("payloads %+v, want the hot alert only", got)
+	}
+}
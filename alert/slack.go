@@ -0,0 +1,197 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("slack", newSlackNotifier)
+}
+
+// slackAlertsPerMessage keeps a message below Slack's limit of 50 blocks.
+const slackAlertsPerMessage = 10
+
+// slackNotifier posts Block Kit messages to a Slack incoming webhook. Alerts
+// of the same tick are sent together.
+type slackNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+type slackSettings struct {
+	URL string `json:"url"`
+}
+
+func newSlackNotifier(name string, raw json.RawMessage) (Notifier, error) {
+	var s slackSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		return nil, fmt.Errorf("settings: url is required")
+	}
+	return NewSlack(name, s.URL), nil
+}
+
+// NewSlack returns a notifier for a Slack incoming-webhook URL.
+func NewSlack(name, webhookURL string) Notifier {
+	return &slackNotifier{
+		name:   name,
+		url:    webhookURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *slackNotifier) Name() string { return n.name }
+
+func (n *slackNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	sent := 0
+	for len(alerts) > 0 {
+		batch := alerts[:min(len(alerts), slackAlertsPerMessage)]
+		alerts = alerts[len(batch):]
+
+		if err := n.post(ctx, slackMessage(batch)); err != nil {
+			return partial(sent, err)
+		}
+		sent += len(batch)
+	}
+	return nil
+}
+
+func (n *slackNotifier) post(ctx context.Context, msg slackPayload) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		// Slack answers errors with a short reason such as "invalid_payload"
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError("slack", resp, reason)
+	}
+	return nil
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"` // fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func plainText(s string) *slackText {
+	return &slackText{Type: "plain_text", Text: s}
+}
+
+func mrkdwn(s string) slackText {
+	return slackText{Type: "mrkdwn", Text: s}
+}
+
+// slackMessage renders alerts as a header, a field section and a context
+// line each, separated by dividers.
+func slackMessage(alerts []Alert) slackPayload {
+	var msg slackPayload
+	fallback := make([]string, 0, len(alerts))
+
+	for i, a := range alerts {
+		if i > 0 {
+			msg.Blocks = append(msg.Blocks, slackBlock{Type: "divider"})
+		}
+		fallback = append(fallback, a.Text)
+
+		header := "⚠ " + a.Title
+		if a.Resolved {
+			header = "✅ Resolved: " + a.Title
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "header", Text: plainText(header)})
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Fields: slackFields(a)})
+
+		var ctxLine []string
+		if a.Cmd != "" {
+			ctxLine = append(ctxLine, "`"+slackEscape(truncateText(a.Cmd, 200))+"`")
+		}
+		ctxLine = append(ctxLine, fmt.Sprintf("%s · %s · <!date^%d^{date_short_pretty} {time_secs}|%s>",
+			a.Rule, a.Severity, a.Time.Unix(), a.Time.Format(time.RFC3339)))
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []slackText{mrkdwn(strings.Join(ctxLine, "\n"))},
+		})
+	}
+
+	msg.Text = strings.Join(fallback, "\n")
+	return msg
+}
+
+func slackFields(a Alert) []slackText {
+	var fields []slackText
+	add := func(label, value string) {
+		fields = append(fields, mrkdwn("*"+label+"*\n"+slackEscape(value)))
+	}
+
+	if a.Pid != 0 {
+		add("Process", a.Comm)
+		add("PID", fmt.Sprint(a.Pid))
+		add("User", a.User)
+	}
+
+	if a.Metric != "" {
+		add("Value", fmt.Sprintf("%s = %.1f", a.Metric, a.Value))
+		add("Threshold", fmt.Sprintf("%g", a.Threshold))
+	} else {
+		add("Condition", a.Expr)
+		add("CPU / MEM", fmt.Sprintf("%.1f%% / %.1f%%", a.CPU, a.PMem))
+	}
+
+	if a.Resolved {
+		reason := "recovered"
+		if a.Exited {
+			reason = "process exited"
+		}
+		add("Resolved", fmt.Sprintf("%s after %s", reason, a.Duration.Round(time.Second)))
+	}
+	add("Host", a.Host)
+	return fields
+}
+
+// slackEscape escapes the characters Slack treats as control sequences.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
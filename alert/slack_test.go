@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slackPayloads decodes the messages received by a recordingServer.
+func slackPayloads(t *testing.T, reqs []recordedRequest) []slackPayload {
+	t.Helper()
+	out := make([]slackPayload, len(reqs))
+	for i, r := range reqs {
+		r.decode(t, &out[i])
+	}
+	return out
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv, reqs := recordingServer(t)
+	n := NewSlack("ops", srv.URL)
+
+	a := testAlert(1)
+	r := testAlert(2)
+	r.Resolved = true
+	r.Duration = 90 * time.Second
+	if err := n.Notify(context.Background(), []Alert{a, r}); err != nil {
+		t.Fatal(err)
+	}
+	got := slackPayloads(t, *reqs)
+	if len(got) != 1 {
+		t.Fatalf("%d messages, want 1", len(got))
+	}
+	p := got[0]
+
+	if p.Text != a.Text+"\n"+r.Text {
+		t.Errorf("fallback text %q", p.Text)
+	}
+	var types []string
+	for _, b := range p.Blocks {
+		types = append(types, b.Type)
+	}
+	if want := "header section context divider header section context"; strings.Join(types, " ") != want {
+		t.Errorf("blocks %v, want %s", types, want)
+	}
+	if h := p.Blocks[0].Text; h.Type != "plain_text" || h.Text != "⚠ High CPU" {
+		t.Errorf("firing header %+v", h)
+	}
+	if h := p.Blocks[4].Text.Text; h != "✅ Resolved: High CPU" {
+		t.Errorf("resolved header %q", h)
+	}
+
+	fields := p.Blocks[1].Fields
+	if fields[0].Type != "mrkdwn" || fields[0].Text != "*Process*\na&lt;b&gt;&amp;c" {
+		t.Errorf("process field %+v, want escaped mrkdwn", fields[0])
+	}
+	ctx := p.Blocks[2].Elements[0].Text
+	if !strings.Contains(ctx, "`/bin/worker --flag &lt;x&gt;`") || !strings.Contains(ctx, "<!date^1700000000^") {
+		t.Errorf("context %q", ctx)
+	}
+	resolved := p.Blocks[5].Fields
+	if last := resolved[len(resolved)-2].Text; last != "*Resolved*\nrecovered after 1m30s" {
+		t.Errorf("resolved field %q", last)
+	}
+}
+
+func TestSlackSplitsMessages(t *testing.T) {
+	srv, reqs := recordingServer(t)
+	n := NewSlack("ops", srv.URL)
+
+	if err := n.Notify(context.Background(), testAlerts(slackAlertsPerMessage+2)); err != nil {
+		t.Fatal(err)
+	}
+	got := slackPayloads(t, *reqs)
+	if len(got) != 2 || strings.Count(got[1].Text, "\n") != 1 {
+		t.Fatalf("%d messages, want 2: %d alerts, then 2", len(got), slackAlertsPerMessage)
+	}
+}
+
+func TestSlackStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusRequestTimeout, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		srv, _ := recordingServer(t, tt.status)
+		err := NewSlack("ops", srv.URL).Notify(context.Background(), []Alert{testAlert(1)})
+		if err == nil {
+			t.Errorf("%d: no error", tt.status)
+			continue
+		}
+		if !strings.Contains(err.Error(), "invalid_payload") {
+			t.Errorf("%d: error %q does not give the reason", tt.status, err)
+		}
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("%d: permanent = %v, want %v", tt.status, IsPermanent(err), tt.permanent)
+		}
+	}
+}
+
+func TestSlackPartialFailure(t *testing.T) {
+	srv, got := recordingServer(t, http.StatusOK, http.StatusServiceUnavailable)
+	err := NewSlack("ops", srv.URL).Notify(context.Background(), testAlerts(slackAlertsPerMessage+2))
+	var p *PartialError
+	if !errors.As(err, &p) || p.Sent != slackAlertsPerMessage {
+		t.Fatalf("error %v, want a PartialError after %d alerts", err, slackAlertsPerMessage)
+	}
+	if len(*got) != 2 {
+		t.Errorf("%d requests, want 2", len(*got))
+	}
+}
@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func init() {
+	Register("webhook", newWebhookNotifier)
+}
+
+// Signature headers sent when a secret is configured. The signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the secret, so receivers can reject forged and replayed requests.
+const (
+	defaultSignatureHeader = "X-Sentinel-Signature"
+	timestampHeader        = "X-Sentinel-Timestamp"
+)
+
+// defaultWebhookBody posts the alert as JSON.
+const defaultWebhookBody = `{{json .}}`
+
+// webhookSettings configures a generic webhook. Template is a text/template
+// executed once per alert with the Alert as data.
+type webhookSettings struct {
+	URL             string            `json:"url"`
+	Method          string            `json:"method,omitempty"` // default POST
+	Headers         map[string]string `json:"headers,omitempty"`
+	ContentType     string            `json:"content_type,omitempty"` // default application/json
+	Template        string            `json:"template,omitempty"`
+	Secret          string            `json:"secret,omitempty"`
+	SignatureHeader string            `json:"signature_header,omitempty"`
+}
+
+type webhookNotifier struct {
+	name   string
+	cfg    webhookSettings
+	body   *template.Template
+	client *http.Client
+}
+
+var webhookFuncs = template.FuncMap{
+	// json encodes a value, e.g. {"cmd": {{json .Cmd}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"rfc3339": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+}
+
+func newWebhookNotifier(name string, raw json.RawMessage) (Notifier, error) {
+	var s webhookSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+
+	if s.URL == "" {
+		return nil, fmt.Errorf("settings: url is required")
+	}
+	if s.Method == "" {
+		s.Method = http.MethodPost
+	}
+	s.Method = strings.ToUpper(s.Method)
+	if s.ContentType == "" {
+		s.ContentType = "application/json"
+	}
+	if s.Template == "" {
+		s.Template = defaultWebhookBody
+	}
+	if s.SignatureHeader == "" {
+		s.SignatureHeader = defaultSignatureHeader
+	}
+
+	body, err := template.New(name).Funcs(webhookFuncs).Option("missingkey=error").Parse(s.Template)
+	if err != nil {
+		return nil, fmt.Errorf("settings: template: %w", err)
+	}
+
+	return &webhookNotifier{
+		name:   name,
+		cfg:    s,
+		body:   body,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (n *webhookNotifier) Name() string { return n.name }
+
+func (n *webhookNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	for i, a := range alerts {
+		var body bytes.Buffer
+		if err := n.body.Execute(&body, a); err != nil {
+			return partial(i, Permanent(fmt.Errorf("template: %w", err)))
+		}
+		if err := n.post(ctx, body.Bytes(), time.Now()); err != nil {
+			return partial(i, err)
+		}
+	}
+	return nil
+}
+
+func (n *webhookNotifier) post(ctx context.Context, body []byte, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, n.cfg.Method, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", n.cfg.ContentType)
+	req.Header.Set("User-Agent", "sentinel")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if n.cfg.Secret != "" {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(timestampHeader, ts)
+		req.Header.Set(n.cfg.SignatureHeader, Sign(n.cfg.Secret, ts, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError("webhook", resp, reason)
+	}
+	return nil
+}
+
+// Sign returns the signature header value for a request body sent at the
+// given Unix timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
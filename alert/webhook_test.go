@@ -0,0 +1,146 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sentinel/config"
+)
+
+func newTestWebhook(t *testing.T, settings map[string]any) Notifier {
+	t.Helper()
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(config.Channel{Name: "hook", Type: "webhook", Settings: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	srv, got := recordingServer(t, http.StatusNoContent, http.StatusNoContent)
+	n := newTestWebhook(t, map[string]any{
+		"url":          srv.URL,
+		"method":       "put",
+		"content_type": "text/plain",
+		"headers":      map[string]string{"Authorization": "Bearer x"},
+		"template":     `{{upper .Rule}} {{.State}} {{json .Cmd}} {{unix .Time}} {{rfc3339 .Time}}`,
+	})
+
+	a := testAlert(0)
+	a.Cmd = `sh -c "echo <hi>"`
+	r := a
+	r.Resolved = true
+	if err := n.Notify(context.Background(), []Alert{a, r}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*got) != 2 {
+		t.Fatalf("%d requests, want one per alert", len(*got))
+	}
+
+	req := (*got)[0]
+	if want := `HOT firing "sh -c \"echo \u003chi\u003e\"" 1700000000 2023-11-14T22:13:20Z`; req.body != want {
+		t.Errorf("body %s, want %s", req.body, want)
+	}
+	if !strings.HasPrefix((*got)[1].body, "HOT resolved ") {
+		t.Errorf("second body %s", (*got)[1].body)
+	}
+	if req.method != http.MethodPut || req.header.Get("Content-Type") != "text/plain" ||
+		req.header.Get("Authorization") != "Bearer x" || req.header.Get("User-Agent") != "sentinel" {
+		t.Errorf("request %s %v", req.method, req.header)
+	}
+	if req.header.Get(defaultSignatureHeader) != "" {
+		t.Errorf("signed without a secret")
+	}
+}
+
+func TestWebhookDefaultBody(t *testing.T) {
+	srv, got := recordingServer(t)
+	n := newTestWebhook(t, map[string]any{"url": srv.URL})
+
+	a := testAlert(1)
+	if err := n.Notify(context.Background(), []Alert{a}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Alert
+	(*got)[0].decode(t, &decoded)
+	if decoded.ID != a.ID || decoded.Pid != a.Pid || decoded.Cmd != a.Cmd || !decoded.Time.Equal(a.Time) {
+		t.Errorf("decoded %+v", decoded)
+	}
+	if (*got)[0].method != http.MethodPost {
+		t.Errorf("sent with %s", (*got)[0].method)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	srv, got := recordingServer(t)
+	n := newTestWebhook(t, map[string]any{
+		"url":              srv.URL,
+		"secret":           "s3cret",
+		"signature_header": "X-Hub-Signature-256",
+	})
+
+	before := time.Now().Unix()
+	if err := n.Notify(context.Background(), testAlerts(1)); err != nil {
+		t.Fatal(err)
+	}
+	req := (*got)[0]
+
+	ts := req.header.Get(timestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sec < before || sec > time.Now().Unix() {
+		t.Fatalf("timestamp header %q", ts)
+	}
+	if sig := req.header.Get("X-Hub-Signature-256"); sig != Sign("s3cret", ts, []byte(req.body)) {
+		t.Errorf("signature %q does not match the body", sig)
+	}
+	if req.header.Get(defaultSignatureHeader) != "" {
+		t.Errorf("default signature header sent too")
+	}
+
+	// Known vector: HMAC-SHA256("key", "1.body")
+	const want = "sha256=91b5374b153842ad05b2c4eab9349b8321b14703165bd3fb8b034dfb8be98ae5"
+	if got := Sign("key", "1", []byte("body")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	srv, got := recordingServer(t, http.StatusUnprocessableEntity)
+	n := newTestWebhook(t, map[string]any{"url": srv.URL})
+	err := n.Notify(context.Background(), testAlerts(2))
+	if !IsPermanent(err) || len(*got) != 1 {
+		t.Errorf("error %v after %d requests, want a permanent error after 1", err, len(*got))
+	}
+
+	// A template failing on the second alert reports the first as sent
+	srv, _ = recordingServer(t)
+	n = newTestWebhook(t, map[string]any{"url": srv.URL, "template": `{{if .Resolved}}{{.Nope}}{{end}}`})
+	alerts := testAlerts(2)
+	alerts[1].Resolved = true
+	err = n.Notify(context.Background(), alerts)
+	var p *PartialError
+	if !errors.As(err, &p) || p.Sent != 1 || !IsPermanent(err) {
+		t.Errorf("error %v, want a permanent PartialError after 1 alert", err)
+	}
+
+	for _, settings := range []map[string]any{
+		{},
+		{"url": srv.URL, "template": "{{"},
+		{"url": srv.URL, "bogus": true},
+	} {
+		raw, _ := json.Marshal(settings)
+		if _, err := New(config.Channel{Name: "hook", Type: "webhook", Settings: raw}); err == nil {
+			t.Errorf("settings %s accepted", raw)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sentinel/config"
+	"sentinel/history"
+)
+
+// runAlerts implements "sentinel alerts list|show|tail".
+func runAlerts(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: sentinel alerts <list|show|tail> [flags]")
+		os.Exit(2)
+	}
+
+	path := history.Path(config.Dir())
+	switch args[0] {
+	case "list":
+		alertsList(path, args[1:])
+	case "show":
+		alertsShow(path, args[1:])
+	case "tail":
+		alertsTail(path, args[1:])
+	default:
+		fmt.Println("unknown alerts subcommand:", args[0])
+		os.Exit(2)
+	}
+}
+
+// filterFlags registers the flags shared by list and tail.
+func filterFlags(fs *flag.FlagSet) func() history.Filter {
+	since := fs.String("since", "", "only alerts after this time (e.g. 12h, 2024-05-01, 2024-05-01 22:00)")
+	until := fs.String("until", "", "only alerts before this time")
+	pid := fs.Int("pid", 0, "only alerts for this PID")
+	comm := fs.String("comm", "", "only processes whose name or command line matches this regexp")
+	user := fs.String("user", "", "only processes of this user")
+	rule := fs.String("rule", "", "only alerts of this rule")
+
+	return func() history.Filter {
+		f := history.Filter{Pid: *pid, User: *user, Rule: *rule}
+		var err error
+		if f.Since, err = parseTimeArg(*since, time.Now()); err != nil {
+			fatalf("invalid -since: %v", err)
+		}
+		if f.Until, err = parseTimeArg(*until, time.Now()); err != nil {
+			fatalf("invalid -until: %v", err)
+		}
+		if *comm != "" {
+			if f.Comm, err = regexp.Compile(*comm); err != nil {
+				fatalf("invalid -comm: %v", err)
+			}
+		}
+		return f
+	}
+}
+
+func alertsList(path string, args []string) {
+	fs := flag.NewFlagSet("alerts list", flag.ExitOnError)
+	filter := filterFlags(fs)
+	limit := fs.Int("n", 0, "show only the last n alerts")
+	fs.Parse(args)
+	f := filter()
+
+	entries := loadEntries(path, &f)
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+	if len(entries) == 0 {
+		fmt.Println("no alerts")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSTATE\tSEVERITY\tRULE\tPID\tCOMM\tDELIVERY")
+	for _, e := range entries {
+		printEntryRow(w, e)
+	}
+	w.Flush()
+}
+
+func alertsShow(path string, args []string) {
+	if len(args) != 1 {
+		fmt.Println("usage: sentinel alerts show <id>")
+		os.Exit(2)
+	}
+
+	e, ok := history.Find(loadEntries(path, nil), args[0])
+	if !ok {
+		fatalf("no single alert matches %q", args[0])
+	}
+
+	a := e.Alert
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", label, value)
+		}
+	}
+	row("ID", a.ID)
+	row("Time", a.Time.Local().Format(time.DateTime))
+	row("State", a.State())
+	row("Rule", fmt.Sprintf("%s (%s)", a.Rule, a.Severity))
+	row("Message", a.Text)
+	row("Condition", a.Expr)
+	if a.Metric != "" {
+		row("Value", fmt.Sprintf("%s = %.2f (threshold %g)", a.Metric, a.Value, a.Threshold))
+	}
+	if a.Pid != 0 {
+		row("Process", fmt.Sprintf("%s (PID %d, user %s)", a.Comm, a.Pid, a.User))
+		row("Command", a.Cmd)
+		row("CPU", fmt.Sprintf("%.1f%%", a.CPU))
+		row("MEM", fmt.Sprintf("%.1f%% (%d KB RSS)", a.PMem, a.RSSKB))
+	}
+	if a.Duration > 0 {
+		row("Duration", a.Duration.Round(time.Second).String())
+	}
+	row("Host", a.Host)
+	w.Flush()
+
+	fmt.Println("\nDeliveries:")
+	if e.Silenced != "" {
+		fmt.Println("  suppressed by", e.Silenced)
+		return
+	}
+	if len(e.Channels) == 0 {
+		fmt.Println("  no channels")
+	}
+	for _, ch := range e.Channels {
+		d, ok := e.Deliveries[ch]
+		switch {
+		case !ok:
+			fmt.Printf("  %-16s pending\n", ch)
+		case d.OK:
+			fmt.Printf("  %-16s ok at %s (attempt %d)\n", ch, d.Time.Local().Format(time.DateTime), d.Attempts)
+		case d.GaveUp:
+			fmt.Printf("  %-16s failed, gave up after %d attempts: %s\n", ch, d.Attempts, d.Error)
+		default:
+			fmt.Printf("  %-16s retrying (attempt %d): %s\n", ch, d.Attempts, d.Error)
+		}
+	}
+}
+
+func alertsTail(path string, args []string) {
+	fs := flag.NewFlagSet("alerts tail", flag.ExitOnError)
+	filter := filterFlags(fs)
+	n := fs.Int("n", 10, "number of alerts to show")
+	follow := fs.Bool("f", false, "keep printing new alerts")
+	fs.Parse(args)
+	f := filter()
+
+	var tail *tailer
+	if *follow {
+		// Before reading, so alerts written meanwhile are not lost
+		tail = newTailer(path)
+	}
+	all := loadEntries(path, nil)
+	entries := filterEntries(all, &f)
+	if len(entries) > *n {
+		entries = entries[len(entries)-*n:]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSTATE\tSEVERITY\tRULE\tPID\tCOMM\tDELIVERY")
+	for _, e := range entries {
+		printEntryRow(w, e)
+	}
+	w.Flush()
+	if tail == nil {
+		return
+	}
+
+	// The tailer started before the read: alerts written in between come
+	// up again and are skipped
+	seen := make(map[string]bool)
+	for _, e := range all {
+		seen[e.Alert.ID] = true
+	}
+	tail.follow(func(line string) {
+		var r history.Record
+		if json.Unmarshal([]byte(line), &r) != nil || r.Kind != history.KindAlert || r.Alert == nil {
+			return
+		}
+		if seen[r.Alert.ID] || !f.Match(r.Alert) {
+			return
+		}
+		seen[r.Alert.ID] = true
+		printEntryRow(w, history.Entries([]history.Record{r})[0])
+		w.Flush()
+	})
+}
+
+// loadEntries reads the history and applies the filter, if any.
+func loadEntries(path string, f *history.Filter) []*history.Entry {
+	records, err := history.ReadAll(path)
+	if err != nil {
+		fatalf("failed to read alert history: %v", err)
+	}
+
+	return filterEntries(history.Entries(records), f)
+}
+
+// filterEntries keeps the entries that match f, all of them if f is nil.
+func filterEntries(entries []*history.Entry, f *history.Filter) []*history.Entry {
+	if f == nil {
+		return entries
+	}
+	var out []*history.Entry
+	for _, e := range entries {
+		if f.Match(&e.Alert) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func printEntryRow(w *tabwriter.Writer, e *history.Entry) {
+	a := e.Alert
+	pid, comm := "-", "-"
+	if a.Pid != 0 {
+		pid = fmt.Sprint(a.Pid)
+		comm = a.Comm
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		a.ID, a.Time.Local().Format(time.DateTime), a.State(), a.Severity, a.Rule, pid, comm, e.Status())
+}
+
+// parseTimeArg accepts a duration before now ("90m", "2h"), a date, a date
+// and time, a time of today, or RFC 3339. Empty means no bound.
+func parseTimeArg(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if strings.HasSuffix(s, "d") {
+		var days int
+		if _, err := fmt.Sscanf(s, "%dd", &days); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+
+	layouts := []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", time.DateOnly}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
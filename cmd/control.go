@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sentinel/daemon"
+	"sentinel/ui"
+)
+
+// controlDaemon sends reload, pause, resume and snapshot requests to the
+// running daemon.
+func controlDaemon(sub string, args []string) {
+	switch sub {
+	case "reload":
+		callDaemon(daemon.Request{Cmd: daemon.CmdReload}, nil)
+		fmt.Println("config reloaded")
+
+	case "pause":
+		fs := flag.NewFlagSet("daemon pause", flag.ExitOnError)
+		dur := fs.Duration("for", 0, "resume automatically after this long")
+		fs.Parse(args)
+		callDaemon(daemon.Request{Cmd: daemon.CmdPause, For: *dur}, nil)
+		if *dur > 0 {
+			fmt.Println("alerting paused for", *dur)
+		} else {
+			fmt.Println("alerting paused until resumed")
+		}
+
+	case "resume":
+		callDaemon(daemon.Request{Cmd: daemon.CmdResume}, nil)
+		fmt.Println("alerting resumed")
+
+	case "snapshot":
+		fs := flag.NewFlagSet("daemon snapshot", flag.ExitOnError)
+		n := fs.Int("n", 15, "number of processes")
+		sortBy := fs.String("sort", "cpu", "sort by cpu, mem, rss, read or write")
+		fs.Parse(args)
+
+		var info daemon.SnapshotInfo
+		callDaemon(daemon.Request{Cmd: daemon.CmdSnapshot, Limit: *n, Sort: *sortBy}, &info)
+		printSnapshot(info)
+	}
+}
+
+func callDaemon(req daemon.Request, out any) {
+	if err := daemon.Call(req, out); err != nil {
+		fatalf("daemon %s: %v", req.Cmd, err)
+	}
+}
+
+func printDaemonStatus(st daemon.Status) {
+	fmt.Printf("daemon: running (pid: %d )\n", st.PID)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  uptime:\t%s\n", time.Since(st.Started).Round(time.Second))
+	fmt.Fprintf(w, "  ticks:\t%d (every %s, last %s ago)\n", st.Ticks, st.Interval,
+		time.Since(st.LastTick).Round(time.Millisecond))
+	fmt.Fprintf(w, "  last scan:\t%s, %d processes\n", st.LastScan.Round(time.Microsecond), st.Procs)
+	fmt.Fprintf(w, "  config:\tversion %d, loaded %s\n", st.ConfigVersion, st.ConfigLoaded.Format(time.DateTime))
+	fmt.Fprintf(w, "  rules:\t%d\n", st.Rules)
+	fmt.Fprintf(w, "  channels:\t%s\n", strings.Join(st.Channels, ", "))
+	if st.Paused {
+		until := "until resumed"
+		if !st.PausedUntil.IsZero() {
+			until = "until " + st.PausedUntil.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "  alerting:\tpaused %s\n", until)
+	} else {
+		fmt.Fprintf(w, "  alerting:\tactive\n")
+	}
+	w.Flush()
+
+	if st.ConfigError != "" {
+		fmt.Println("config problems:")
+		for _, line := range strings.Split(st.ConfigError, "\n") {
+			fmt.Println("  " + line)
+		}
+	}
+
+	if len(st.Active) == 0 {
+		fmt.Println("active alerts: none")
+		return
+	}
+	fmt.Println("active alerts:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, a := range st.Active {
+		proc := "system"
+		if a.Pid != 0 {
+			proc = fmt.Sprintf("%s (%d)", a.Comm, a.Pid)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\tsince %s\n", a.State, a.Rule, proc, a.Since.Format(time.TimeOnly))
+	}
+	w.Flush()
+}
+
+func printSnapshot(info daemon.SnapshotInfo) {
+	fmt.Printf("%s  cpu %.1f%%  mem %.1f%%  load %.2f %.2f %.2f  tasks %d\n\n",
+		info.Time.Format(time.DateTime), info.CPU, info.MemUsed,
+		info.Loads[0], info.Loads[1], info.Loads[2], info.Tasks)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "PID\tUSER\tCPU%\tMEM%\tRSS\tREAD/s\tWRITE/s\tTHR\t  COMMAND")
+	for _, p := range info.Procs {
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%.1f\t%s\t%s\t%s\t%d\t  %s\n",
+			p.Pid, p.User, p.CPU, p.PMem, ui.FormatKB(p.RSSKB),
+			ui.FormatRate(p.ReadBps), ui.FormatRate(p.WriteBps), p.Threads, p.Comm)
+	}
+	w.Flush()
+}
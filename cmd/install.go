@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const unitName = "sentinel.service"
+
+// installDaemon implements "sentinel daemon install --user|--system": it
+// writes a systemd unit running "sentinel daemon run" as a Type=notify
+// service with a watchdog.
+func installDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon install", flag.ExitOnError)
+	userUnit := fs.Bool("user", false, "install a user unit in ~/.config/systemd/user")
+	systemUnit := fs.Bool("system", false, "install a system unit in /etc/systemd/system")
+	runAs := fs.String("run-as", "", "system unit: account to run the daemon as (default: the invoking user)")
+	watchdog := fs.Duration("watchdog", 30*time.Second, "WatchdogSec of the unit, 0 disables the watchdog")
+	force := fs.Bool("force", false, "overwrite an existing unit file")
+	printOnly := fs.Bool("print", false, "print the unit instead of writing it")
+	fs.Parse(args)
+
+	if *userUnit == *systemUnit {
+		fmt.Println("usage: sentinel daemon install --user|--system [flags]")
+		os.Exit(2)
+	}
+	// WatchdogSec has a resolution of a second, less would disable it
+	if *watchdog != 0 && *watchdog < time.Second {
+		fatalf("-watchdog must be 0 or at least 1s, got %s", *watchdog)
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fatalf("cannot locate the sentinel binary: %v", err)
+	}
+
+	var path, unit string
+	if *userUnit {
+		dir := os.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = filepath.Join(os.Getenv("HOME"), ".config")
+		}
+		path = filepath.Join(dir, "systemd", "user", unitName)
+		unit = unitFile(exe, *watchdog, nil, "default.target")
+	} else {
+		u, err := serviceUser(*runAs)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		path = filepath.Join("/etc/systemd/system", unitName)
+		unit = unitFile(exe, *watchdog, u, "multi-user.target")
+	}
+
+	if *printOnly {
+		fmt.Print(unit)
+		return
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fatalf("%s already exists, use -force to overwrite it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fatalf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			fatalf("cannot write %s: %v (try sudo)", path, err)
+		}
+		fatalf("failed to write unit: %v", err)
+	}
+
+	systemctl := "systemctl"
+	if *userUnit {
+		systemctl += " --user"
+	}
+	fmt.Println("wrote", path)
+	fmt.Println("enable and start it with:")
+	fmt.Printf("  %s daemon-reload\n", systemctl)
+	fmt.Printf("  %s enable --now %s\n", systemctl, unitName)
+	if *userUnit {
+		if u, err := user.Current(); err == nil {
+			fmt.Println("to keep it running after logout: loginctl enable-linger", u.Username)
+		}
+	} else if u, err := serviceUser(*runAs); err == nil {
+		fmt.Println("to keep its runtime dir when", u.Username, "logs out: loginctl enable-linger", u.Username)
+	}
+}
+
+// serviceUser resolves the account a system unit runs as: name, the user
+// who invoked sudo, or the current user.
+func serviceUser(name string) (*user.User, error) {
+	if name == "" {
+		name = os.Getenv("SUDO_USER")
+	}
+	if name == "" {
+		return user.Current()
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	return u, nil
+}
+
+// unitFile renders the unit. System units set User and HOME since the
+// config and state live in ~/.sentinel of that account, and the account's
+// XDG_RUNTIME_DIR so that the daemon puts its socket and lock where the CLI
+// of that user looks for them.
+func unitFile(exe string, watchdog time.Duration, u *user.User, wantedBy string) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Sentinel process monitor and alert daemon\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+	if u != nil {
+		// Creates /run/user/<uid> when the user has no session
+		fmt.Fprintf(&b, "Wants=user-runtime-dir@%s.service\n", u.Uid)
+		fmt.Fprintf(&b, "After=user-runtime-dir@%s.service\n", u.Uid)
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=notify\n")
+	b.WriteString("NotifyAccess=main\n")
+	fmt.Fprintf(&b, "ExecStart=%s daemon run\n", systemdQuote(exe))
+	fmt.Fprintf(&b, "ExecReload=%s daemon reload\n", systemdQuote(exe))
+	if watchdog > 0 {
+		fmt.Fprintf(&b, "WatchdogSec=%d\n", int(watchdog.Round(time.Second).Seconds()))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n")
+	if u != nil {
+		fmt.Fprintf(&b, "User=%s\n", u.Username)
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote("HOME="+u.HomeDir))
+		fmt.Fprintf(&b, "Environment=XDG_RUNTIME_DIR=/run/user/%s\n", u.Uid)
+	}
+	b.WriteString("\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=%s\n", wantedBy)
+	return b.String()
+}
+
+// systemdQuote quotes a path for a unit file if it needs it.
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
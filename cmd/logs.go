@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/daemon"
+)
+
+// daemonLogs implements "sentinel daemon logs [-f] [-n lines] [-level lvl]".
+func daemonLogs(args []string) {
+	fs := flag.NewFlagSet("daemon logs", flag.ExitOnError)
+	n := fs.Int("n", 50, "number of lines to show")
+	follow := fs.Bool("f", false, "keep printing new lines")
+	levelName := fs.String("level", "", "only show records at or above this level")
+	fs.Parse(args)
+
+	minLevel := slog.LevelDebug
+	if *levelName != "" {
+		var err error
+		if minLevel, err = daemon.ParseLevel(*levelName); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	show := func(line string) bool {
+		lvl, ok := lineLevel(line)
+		return !ok || lvl >= minLevel
+	}
+
+	// The logs matter most when the config is broken, so read them with
+	// the default rotation settings rather than give up
+	logCfg := config.Default().Log
+	if cfg, err := config.LoadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	} else {
+		logCfg = cfg.Log
+	}
+	path := daemon.LogPath(config.Dir())
+	var tail *tailer
+	if *follow {
+		// Before reading, so lines written meanwhile are not lost
+		tail = newTailer(path)
+	}
+	files := daemon.LogFiles(path, logCfg.MaxBackups)
+	if len(files) == 0 && !*follow {
+		fmt.Println("no daemon log at", path)
+		return
+	}
+
+	// Collect the last n lines, reading the newest files first
+	var lines []string
+	for i := len(files) - 1; i >= 0 && len(lines) < *n; i-- {
+		data, err := os.ReadFile(files[i])
+		if err != nil {
+			fatalf("failed to read daemon log: %v", err)
+		}
+		var fileLines []string
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 64<<10), 1<<20)
+		for sc.Scan() {
+			if show(sc.Text()) {
+				fileLines = append(fileLines, sc.Text())
+			}
+		}
+		lines = append(fileLines, lines...)
+	}
+	if len(lines) > *n {
+		lines = lines[len(lines)-*n:]
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if tail != nil {
+		tail.follow(func(line string) {
+			if show(line) {
+				fmt.Println(line)
+			}
+		})
+	}
+}
+
+// tailer reads the lines appended to a file, reopening it when it is
+// rotated or truncated.
+type tailer struct {
+	path    string
+	f       *os.File
+	offset  int64
+	partial []byte
+	buf     []byte
+}
+
+// newTailer starts at the current end of the file at path, so a caller
+// can read what is already there and then follow from that point.
+func newTailer(path string) *tailer {
+	t := &tailer{path: path, buf: make([]byte, 64<<10)}
+	if f, err := os.Open(path); err == nil {
+		t.f = f
+		t.offset, _ = f.Seek(0, io.SeekEnd)
+	}
+	return t
+}
+
+// drain passes the complete lines past offset to line.
+func (t *tailer) drain(line func(string)) {
+	for {
+		k, err := t.f.ReadAt(t.buf, t.offset)
+		t.offset += int64(k)
+		t.partial = append(t.partial, t.buf[:k]...)
+		for {
+			i := bytes.IndexByte(t.partial, '\n')
+			if i < 0 {
+				break
+			}
+			line(string(t.partial[:i]))
+			t.partial = t.partial[i+1:]
+		}
+		if err == io.EOF || k == 0 {
+			return
+		}
+		if err != nil {
+			fatalf("failed to read %s: %v", t.path, err)
+		}
+	}
+}
+
+// follow passes every line appended to the file to line. It never returns.
+func (t *tailer) follow(line func(string)) {
+	for ; ; time.Sleep(500 * time.Millisecond) {
+		st, err := os.Stat(t.path)
+		if err != nil {
+			continue
+		}
+		if t.f != nil {
+			fst, err := t.f.Stat()
+			if err != nil || !os.SameFile(st, fst) || st.Size() < t.offset {
+				// Rotated or truncated: finish the old file, then start over
+				if err == nil && !os.SameFile(st, fst) {
+					t.drain(line)
+				}
+				t.f.Close()
+				t.f, t.offset, t.partial = nil, 0, nil
+			}
+		}
+		if t.f == nil {
+			if t.f, err = os.Open(t.path); err != nil {
+				continue
+			}
+		}
+		t.drain(line)
+	}
+}
+
+// lineLevel extracts the level of a logfmt or JSON log record.
+func lineLevel(line string) (slog.Level, bool) {
+	var name string
+	if strings.HasPrefix(line, "{") {
+		var rec struct {
+			Level string `json:"level"`
+		}
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			return 0, false
+		}
+		name = rec.Level
+	} else {
+		_, rest, ok := strings.Cut(line, " level=")
+		if !ok {
+			return 0, false
+		}
+		name, _, _ = strings.Cut(rest, " ")
+	}
+
+	var lvl slog.Level
+	if lvl.UnmarshalText([]byte(name)) != nil {
+		return 0, false
+	}
+	return lvl, true
+}
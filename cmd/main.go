@@ -3,19 +3,26 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"sentinel/alert"
+	"sentinel/config"
 	"sentinel/daemon"
+	"sentinel/model"
 	"sentinel/monitor"
 	"sentinel/proc"
+	"sentinel/ui"
 )
 
 func main() {
@@ -32,9 +39,9 @@ func main() {
 		runTUI(hz)
 
 	case "daemon":
-		// subcommands: start | run | stop | status
+		// subcommands: start | run | stop | status | reload | pause | resume | snapshot | logs | install
 		if len(os.Args) < 3 {
-			fmt.Println("usage: sentinel daemon <start|stop|status>")
+			fmt.Println("usage: sentinel daemon <start|run|stop|status|reload|pause|resume|snapshot|logs|install>")
 			os.Exit(2)
 		}
 		sub := os.Args[2]
@@ -42,16 +49,28 @@ func main() {
 		case "start":
 			startDaemon(hz)
 		case "run":
-			runDaemon(hz)
+			runDaemon(hz, os.Args[3:])
 		case "stop":
-			stopDaemon()
+			stopDaemon(os.Args[3:])
 		case "status":
 			statusDaemon()
+		case "reload", "pause", "resume", "snapshot":
+			controlDaemon(sub, os.Args[3:])
+		case "logs":
+			daemonLogs(os.Args[3:])
+		case "install":
+			installDaemon(os.Args[3:])
 		default:
 			fmt.Println("unknown daemon subcommand:", sub)
 			os.Exit(2)
 		}
 
+	case "alerts":
+		runAlerts(os.Args[2:])
+
+	case "silence":
+		runSilence(os.Args[2:])
+
 	case "help":
 		usage()
 
@@ -66,46 +85,92 @@ func usage() {
         Sentinel commands:
         sentinel tui       → start the TUI monitor
         sentinel daemon    → start background alert daemon
+        sentinel alerts    → list|show|tail alerts sent by the daemon
+        sentinel silence   → add|list|expire alert silences
         sentinel help      → show help
     `)
 }
 
 func runTUI(hz int) {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	interval := 1500 * time.Millisecond
 
-	engine := monitor.NewEngine()
-	engine.Run(ctx, interval, hz, log.New(os.Stderr, "[sentinel] ", log.LstdFlags))
+	// Settings edited in the TUI are saved over the file, so refuse to start
+	// on one that does not parse rather than replace it with defaults
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	model.DefaultHZ = hz
+	sampler := monitor.NewSampler(interval)
+	go sampler.Run(ctx)
+
+	if err := ui.Run(ctx, cfg, sampler); err != nil {
+		log.New(os.Stderr, "[sentinel] ", log.LstdFlags).Println(err)
+	}
 }
 
-// runDaemon runs the daemon in the foreground. Used by the background child process.
-func runDaemon(hz int) {
+// runDaemon runs the daemon in the foreground. Used by the background child
+// process and by systemd units.
+func runDaemon(hz int, args []string) {
+	fs := flag.NewFlagSet("daemon run", flag.ExitOnError)
+	detached := fs.Bool("detached", false, "started by daemon start: only write startup errors to stderr")
+	fs.Parse(args)
+
+	inst, err := daemon.Lock()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sentinel daemon:", err)
+		os.Exit(1)
+	}
+	defer inst.Release()
+
 	// Graceful shutdown on SIGINT/SIGTERM
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	logger := log.New(os.Stderr, "[sentinel-daemon] ", log.LstdFlags)
-	d := daemon.New(1*time.Second, hz, logger)
+	// The detached child's stderr is the startup log, keep it to startup
+	// errors rather than copy the whole daemon log into it
+	var console io.Writer = os.Stderr
+	if *detached {
+		console = nil
+	}
+
+	model.DefaultHZ = hz
+	d := daemon.New(1*time.Second, hz, console)
 	_ = d.Run(ctx)
 }
 
-// startDaemon starts a detached background process and exits 0 immediately.
+// startDaemon starts a detached background process and exits once it
+// answers on the control socket.
 func startDaemon(hz int) {
-	pidPath := daemonPIDPath()
+	// Under a Type=notify systemd unit the service manager supervises the
+	// process itself, forking would only hide the daemon from it
+	if os.Getenv("NOTIFY_SOCKET") != "" {
+		runDaemon(hz, nil)
+		return
+	}
 
 	// If already running, don't start another
-	if pid, err := readPID(pidPath); err == nil && pid > 0 {
-		if processExists(pid) {
-			fmt.Println("daemon already running (pid:", pid, ")")
-			os.Exit(0)
-		}
+	if pid, running := daemon.Running(); running {
+		fmt.Println("daemon already running (pid:", describePID(pid), ")")
+		os.Exit(0)
+	}
+
+	// Launch a detached child process: sentinel daemon run. Its stderr goes
+	// to a file so that errors before its log is open, such as failing to
+	// take the lock, can be reported here
+	startLog, err := createStartLog()
+	if err != nil {
+		fatalf("failed to start daemon: %v", err)
 	}
+	defer startLog.Close()
 
-	// Launch a detached child process: sentinel daemon run
 	exe, _ := os.Executable()
-	cmd := exec.Command(exe, "daemon", "run")
+	cmd := exec.Command(exe, "daemon", "run", "-detached")
 	cmd.Stdout = nil
-	cmd.Stderr = nil
+	cmd.Stderr = startLog
 	cmd.Stdin = nil
 
 	// Detach from parent session
@@ -116,69 +181,143 @@ func startDaemon(hz int) {
 		os.Exit(1)
 	}
 
-	// Write child PID and exit 0
-	_ = writePID(pidPath, cmd.Process.Pid)
-	fmt.Println("daemon started (pid:", cmd.Process.Pid, ")")
-	os.Exit(0)
+	// The child takes the lock and writes its PID file itself; wait until it
+	// is up or has given up, e.g. because another start won the race
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case err := <-exited:
+			if pid, running := daemon.Running(); running {
+				fmt.Println("daemon already running (pid:", describePID(pid), ")")
+				os.Exit(0)
+			}
+			out, _ := os.ReadFile(startLog.Name())
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				fatalf("daemon exited during startup (%v): %s", err, msg)
+			}
+			fatalf("daemon exited during startup (%v), see sentinel daemon logs", err)
+
+		case <-deadline:
+			fmt.Println("daemon started (pid:", cmd.Process.Pid, "), control socket not answering yet")
+			os.Exit(0)
+
+		case <-time.After(100 * time.Millisecond):
+			var st daemon.Status
+			if daemon.Call(daemon.Request{Cmd: daemon.CmdStatus}, &st) == nil && st.PID == cmd.Process.Pid {
+				fmt.Println("daemon started (pid:", cmd.Process.Pid, ")")
+				os.Exit(0)
+			}
+		}
+	}
 }
 
-func stopDaemon() {
-	pidPath := daemonPIDPath()
-	pid, err := readPID(pidPath)
-	if err != nil || pid <= 0 {
+// createStartLog truncates the file receiving the stderr of a daemon being
+// started. It lives in the state dir, next to the daemon log, since the
+// runtime dir may be the very thing the daemon fails on.
+func createStartLog() (*os.File, error) {
+	path := filepath.Join(config.Dir(), "daemon.start.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+}
+
+// stopDaemon asks the daemon to shut down and waits for it to release its
+// lock, killing it if it does not exit within the timeout.
+func stopDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon stop", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Second, "how long to wait for a graceful shutdown before killing the daemon")
+	fs.Parse(args)
+
+	pid, running := daemon.Running()
+	if !running {
 		fmt.Println("daemon not running")
-		os.Exit(0)
+		return
 	}
 
-	// Send SIGTERM
-	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
-		// If process not found, treat as stopped
-		if !errors.Is(err, syscall.ESRCH) {
-			fmt.Fprintln(os.Stderr, "failed to stop daemon:", err)
-			os.Exit(1)
+	// Ask over the control socket first, signal the PID as a fallback
+	if err := daemon.Call(daemon.Request{Cmd: daemon.CmdStop}, nil); err != nil {
+		if pid == 0 {
+			fatalf("failed to stop daemon: control socket unreachable (%v) and no valid PID file", err)
+		}
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+			fatalf("failed to stop daemon: %v", err)
 		}
 	}
+	if waitStopped(*timeout) {
+		fmt.Println("daemon stopped")
+		return
+	}
 
-	// Best-effort cleanup
-	_ = os.Remove(pidPath)
-	fmt.Println("daemon stopped")
+	// Only a PID verified by daemon.Running is ever killed
+	if pid == 0 {
+		fatalf("daemon still running after %s, PID unknown", *timeout)
+	}
+	fmt.Fprintf(os.Stderr, "daemon did not stop within %s, killing pid %d\n", *timeout, pid)
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		fatalf("failed to kill daemon: %v", err)
+	}
+	if !waitStopped(5 * time.Second) {
+		fatalf("daemon (pid %d) still running after SIGKILL", pid)
+	}
+	fmt.Println("daemon killed")
 }
 
-func statusDaemon() {
-	pidPath := daemonPIDPath()
-	pid, err := readPID(pidPath)
-	if err != nil || pid <= 0 || !processExists(pid) {
-		fmt.Println("daemon: stopped")
-		return
+// waitStopped polls until no daemon holds the lock.
+func waitStopped(timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); ; time.Sleep(100 * time.Millisecond) {
+		if _, running := daemon.Running(); !running {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
 	}
-	fmt.Println("daemon: running (pid:", pid, ")")
 }
 
-// PID file helpers
-func daemonPIDPath() string {
-	dir := filepath.Join(os.TempDir(), "sentinel")
-	_ = os.MkdirAll(dir, 0o755)
-	return filepath.Join(dir, "daemon.pid")
+func statusDaemon() {
+	var st daemon.Status
+	if err := daemon.Call(daemon.Request{Cmd: daemon.CmdStatus}, &st); err == nil {
+		printDaemonStatus(st)
+	} else if pid, running := daemon.Running(); running {
+		fmt.Println("daemon: running (pid:", describePID(pid), "), control socket unreachable")
+	} else {
+		fmt.Println("daemon: stopped")
+	}
+	printOutboxStatus()
 }
 
-func writePID(path string, pid int) error {
-	return os.WriteFile(path, []byte(strconv.Itoa(pid)), 0o644)
+func describePID(pid int) string {
+	if pid == 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(pid)
 }
 
-func readPID(path string) (int, error) {
-	b, err := os.ReadFile(path)
+// printOutboxStatus reports notifications that are waiting for a retry or
+// were given up on.
+func printOutboxStatus() {
+	outbox, err := alert.OpenOutbox(alert.OutboxDir(config.Dir()), nil)
 	if err != nil {
-		return 0, err
-	}
-	pid, err := strconv.Atoi(string(b))
-	if err != nil {
-		return 0, err
+		fmt.Println("outbox: unavailable:", err)
+		return
 	}
-	return pid, nil
-}
+	pending, _ := outbox.Pending()
+	failed, _ := outbox.Failed()
+	fmt.Printf("outbox: %d pending, %d failed\n", len(pending), len(failed))
 
-func processExists(pid int) bool {
-	// Signal 0 checks for existence without sending a real signal
-	err := syscall.Kill(pid, 0)
-	return err == nil || err == syscall.EPERM
+	for _, e := range pending {
+		if e.Attempts == 0 {
+			continue
+		}
+		fmt.Printf("  retrying  %-16s %d alert(s) since %s, %d attempt(s), next %s: %s\n",
+			e.Channel, len(e.Alerts), e.Created.Format(time.DateTime), e.Attempts,
+			e.NextAttempt.Format(time.TimeOnly), e.LastError)
+	}
+	for _, e := range failed {
+		fmt.Printf("  failed    %-16s %d alert(s) from %s, %d attempt(s): %s\n",
+			e.Channel, len(e.Alerts), e.Created.Format(time.DateTime), e.Attempts, e.LastError)
+	}
 }
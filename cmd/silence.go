@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sentinel/config"
+	"sentinel/silence"
+)
+
+// runSilence implements "sentinel silence add|list|expire".
+func runSilence(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: sentinel silence <add|list|expire> [flags]")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "add":
+		silenceAdd(args[1:])
+	case "list":
+		silenceList(args[1:])
+	case "expire":
+		silenceExpire(args[1:])
+	default:
+		fmt.Println("unknown silence subcommand:", args[0])
+		os.Exit(2)
+	}
+}
+
+func silenceAdd(args []string) {
+	fs := flag.NewFlagSet("silence add", flag.ExitOnError)
+	rules := fs.String("rule", "", "comma-separated rule names (default: all rules)")
+	comm := fs.String("comm", "", "regexp on the process name")
+	cmdline := fs.String("cmdline", "", "regexp on the command line")
+	usr := fs.String("user", "", "process owner")
+	start := fs.String("start", "", "start time (default: now)")
+	end := fs.String("end", "", "end time")
+	dur := fs.Duration("for", 0, "duration, instead of -end")
+	comment := fs.String("comment", "", "why the silence exists")
+	fs.Parse(args)
+
+	now := time.Now()
+	s := config.Silence{
+		ID:      newSilenceID(),
+		Match:   config.RuleMatch{Comm: *comm, Cmdline: *cmdline, User: *usr},
+		Start:   now,
+		Comment: *comment,
+	}
+	if *rules != "" {
+		s.Rules = strings.Split(*rules, ",")
+	}
+	if u, err := user.Current(); err == nil {
+		s.CreatedBy = u.Username
+	}
+
+	var err error
+	if *start != "" {
+		if s.Start, err = parseTimeArg(*start, now); err != nil {
+			fatalf("invalid -start: %v", err)
+		}
+	}
+	switch {
+	case *dur > 0:
+		s.End = s.Start.Add(*dur)
+	case *end != "":
+		if s.End, err = parseFutureTimeArg(*end, now); err != nil {
+			fatalf("invalid -end: %v", err)
+		}
+	default:
+		fatalf("silence add: -for or -end is required")
+	}
+
+	// Validate with the same code the daemon uses
+	if _, errs := silence.Compile(&config.SentinelConfig{Silences: []config.Silence{s}}); len(errs) > 0 {
+		fatalf("invalid silence: %v", errs[0])
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+	cfg.Silences = append(pruneSilences(cfg.Silences, now), s)
+	if err := config.SaveConfig(cfg); err != nil {
+		fatalf("failed to save config: %v", err)
+	}
+	fmt.Printf("silence %s active until %s\n", s.ID, s.End.Format(time.DateTime))
+}
+
+func silenceList(args []string) {
+	fs := flag.NewFlagSet("silence list", flag.ExitOnError)
+	all := fs.Bool("a", false, "include expired silences")
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+	now := time.Now()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tSTART\tEND\tRULES\tMATCH\tCOMMENT")
+	for _, s := range cfg.Silences {
+		state := "active"
+		switch {
+		case !now.Before(s.End):
+			state = "expired"
+		case now.Before(s.Start):
+			state = "pending"
+		}
+		if state == "expired" && !*all {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, state,
+			s.Start.Format(time.DateTime), s.End.Format(time.DateTime),
+			listOrAll(s.Rules), describeMatch(s.Match), s.Comment)
+	}
+	w.Flush()
+
+	set, errs := silence.Compile(cfg)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	windows := set.Windows(now)
+	if len(windows) == 0 {
+		return
+	}
+
+	fmt.Println("\nMaintenance windows:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tSTATE\tRULES\tMATCH")
+	for _, st := range windows {
+		mw := st.Window
+		state := "next " + st.Start.Format("Mon 2006-01-02 15:04")
+		if st.Active {
+			state = "active until " + st.End.Format("Mon 15:04")
+		}
+		days := "daily"
+		if len(mw.Days) > 0 {
+			days = strings.Join(mw.Days, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s %s for %s\t%s\t%s\t%s\n", mw.Name, days, mw.Start,
+			time.Duration(mw.Duration), state, listOrAll(mw.Rules), describeMatch(mw.Match))
+	}
+	w.Flush()
+}
+
+func silenceExpire(args []string) {
+	if len(args) != 1 {
+		fmt.Println("usage: sentinel silence expire <id>")
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+
+	now := time.Now()
+	for i := range cfg.Silences {
+		s := &cfg.Silences[i]
+		if s.ID != args[0] {
+			continue
+		}
+		if !now.Before(s.End) {
+			fmt.Println("silence", s.ID, "already expired")
+			return
+		}
+		s.End = now
+		if s.Start.After(now) {
+			s.Start = now.Add(-time.Second)
+		}
+		id := s.ID
+		cfg.Silences = pruneSilences(cfg.Silences, now)
+		if err := config.SaveConfig(cfg); err != nil {
+			fatalf("failed to save config: %v", err)
+		}
+		fmt.Println("silence", id, "expired")
+		return
+	}
+	fatalf("no silence with id %q", args[0])
+}
+
+// silenceKeepExpired is how long expired silences stay in the config, for
+// "silence list -a", before add or expire drops them.
+const silenceKeepExpired = 24 * time.Hour
+
+// pruneSilences drops the silences that expired more than
+// silenceKeepExpired before now.
+func pruneSilences(list []config.Silence, now time.Time) []config.Silence {
+	return slices.DeleteFunc(list, func(s config.Silence) bool {
+		return now.Sub(s.End) > silenceKeepExpired
+	})
+}
+
+// parseFutureTimeArg is parseTimeArg where durations count forward from now.
+func parseFutureTimeArg(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	return parseTimeArg(s, now)
+}
+
+func newSilenceID() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func listOrAll(list []string) string {
+	if len(list) == 0 {
+		return "*"
+	}
+	return strings.Join(list, ",")
+}
+
+func describeMatch(m config.RuleMatch) string {
+	var parts []string
+	if m.Comm != "" {
+		parts = append(parts, "comm=~"+m.Comm)
+	}
+	if m.Cmdline != "" {
+		parts = append(parts, "cmdline=~"+m.Cmdline)
+	}
+	if m.User != "" {
+		parts = append(parts, "user="+m.User)
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, " ")
+}
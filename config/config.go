@@ -1,50 +1,130 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
-var (
-	configDir  = filepath.Join(os.Getenv("HOME"), ".sentinel")
-	configPath = filepath.Join(configDir, "config.json")
-)
-
+// LoadConfig reads the config file, creating it with defaults if it does
+// not exist. A file that cannot be parsed is left untouched and reported
+// with its line and column.
 func LoadConfig() (*SentinelConfig, error) {
-	os.MkdirAll(configDir, 0755)
+	configPath := ConfigPath()
+	os.MkdirAll(Dir(), 0755)
 
 	data, err := os.ReadFile(configPath)
-	if err != nil {
-		cfg := defaultConfig()
+	if errors.Is(err, os.ErrNotExist) {
+		cfg := Default()
 		_ = SaveConfig(cfg)
 		return cfg, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	var cfg SentinelConfig
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		cfg := defaultConfig()
-		_ = SaveConfig(cfg)
-		return cfg, nil
+		return nil, parseError(configPath, data, err)
+	}
+	if cfg.Webhooks == nil {
+		cfg.Webhooks = map[string]Webhook{}
 	}
 
 	return &cfg, nil
 }
 
+// SaveConfig writes the config atomically: readers, including the daemon's
+// file watcher, see either the old or the new file, never a partial one.
 func SaveConfig(cfg *SentinelConfig) error {
-	data, _ := json.MarshalIndent(cfg, "", "  ")
-	return os.WriteFile(configPath, data, 0644)
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(Dir(), ".config.json-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), ConfigPath())
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// SaveSettings writes cfg like SaveConfig but keeps the silences of the file
+// on disk: "sentinel silence" edits them while the TUI holds an older copy
+// of the config. A file that no longer parses is left untouched.
+func SaveSettings(cfg *SentinelConfig) error {
+	disk, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Silences = disk.Silences
+	return SaveConfig(cfg)
 }
 
-func defaultConfig() *SentinelConfig {
+// Default returns the config written on first run.
+func Default() *SentinelConfig {
 	return &SentinelConfig{
-		CPUThreshold:  80,
-		MemThreshold:  80,
-		ActiveWebhook: "",
-		Webhooks:      map[string]string{},
+		CPUThreshold:    80,
+		MemThreshold:    80,
+		SysMemThreshold: 90,
+		SwapThreshold:   50,
+		ActiveWebhook:   "",
+		Webhooks:        map[string]Webhook{},
 	}
 }
 
+// parseError turns a JSON decoding error into "file:line:col: message".
+func parseError(path string, data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		if typeErr.Field != "" {
+			err = fmt.Errorf("%s: cannot use %s as %s", typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+	default:
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Offset counts the bytes read, up to and including the offending one
+	before := data[:min(int(offset), len(data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := max(len(before)-bytes.LastIndexByte(before, '\n')-1, 1)
+	return fmt.Errorf("%s:%d:%d: %w", path, line, col, err)
+}
+
 func ConfigPath() string {
-    return configPath
-}
\ No newline at end of file
+	return filepath.Join(Dir(), "config.json")
+}
+
+// Dir returns the sentinel state directory, ~/.sentinel. It follows $HOME,
+// so tests can point it at a temp dir.
+func Dir() string {
+	return filepath.Join(os.Getenv("HOME"), ".sentinel")
+}
@@ -1,8 +1,211 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
 type SentinelConfig struct {
-	CPUThreshold  float64            `json:"cpu_threshold"`
-	MemThreshold  float64            `json:"mem_threshold"`
+	CPUThreshold float64 `json:"cpu_threshold"`
+	MemThreshold float64 `json:"mem_threshold"`
+
+	// System-wide thresholds, 0 disables the check
+	SysMemThreshold float64 `json:"sys_mem_threshold"`
+	SwapThreshold   float64 `json:"swap_threshold"`
+
+	// Per-process disk I/O thresholds in MB/s, 0 disables the check
+	IOReadThresholdMB  float64 `json:"io_read_threshold_mb"`
+	IOWriteThresholdMB float64 `json:"io_write_threshold_mb"`
+
+	// How long a process must stay above the thresholds above before their
+	// built-in rules fire, 30s when unset; "0s" fires on the first sample
+	BuiltinFor *Duration `json:"builtin_for,omitempty"`
+
+	// Alert rules evaluated by the daemon in addition to the thresholds above
+	Rules []AlertRule `json:"rules,omitempty"`
+
+	// TUI column IDs in display order, empty means the default layout
+	Columns []string `json:"columns,omitempty"`
+
 	ActiveWebhook string             `json:"active_webhook"`
-	Webhooks      map[string]string  `json:"webhooks"`
-}
\ No newline at end of file
+	Webhooks      map[string]Webhook `json:"webhooks"`
+
+	// Notification channels and the routes that select them. Without routes
+	// every alert goes to every channel, including the active webhook.
+	Channels []Channel `json:"channels,omitempty"`
+	Routes   []Route   `json:"routes,omitempty"`
+
+	// Alerts matching an active silence or maintenance window are recorded
+	// in the history but not sent
+	Silences    []Silence           `json:"silences,omitempty"`
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty"`
+
+	// Daemon log file settings
+	Log LogConfig `json:"log"`
+}
+
+// LogConfig controls the daemon log, ~/.sentinel/daemon.log. Zero values
+// select the defaults: level "info", format "logfmt", rotation at 10 MB or
+// 7 days, keeping 5 old files. Only the level is applied on reload.
+type LogConfig struct {
+	Level      string `json:"level,omitempty"`  // debug, info, warn or error
+	Format     string `json:"format,omitempty"` // logfmt or json
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`
+	MaxAgeDays int    `json:"max_age_days,omitempty"`
+	MaxBackups int    `json:"max_backups,omitempty"`
+}
+
+// Silence suppresses notifications for matching alerts between Start and
+// End. Empty Rules and Match fields match everything; a process matcher
+// never matches system-wide alerts.
+type Silence struct {
+	ID        string    `json:"id"`
+	Rules     []string  `json:"rules,omitempty"`
+	Match     RuleMatch `json:"match,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+// MaintenanceWindow is a recurring silence, e.g. every Sunday from 02:00
+// for 3h. Start is a local "HH:MM" time and the window may cross midnight.
+// Days lists the weekdays the window starts on ("mon".."sun" or
+// "monday".."sunday"), empty meaning every day.
+type MaintenanceWindow struct {
+	Name     string    `json:"name"`
+	Days     []string  `json:"days,omitempty"`
+	Start    string    `json:"start"`
+	Duration Duration  `json:"duration"`
+	Rules    []string  `json:"rules,omitempty"`
+	Match    RuleMatch `json:"match,omitempty"`
+}
+
+// Webhook is an incoming-webhook URL. In the config file it is either the
+// plain URL or an object with the URL and the payload type ("discord",
+// "slack"); for plain URLs the type is guessed from the host.
+type Webhook struct {
+	URL  string `json:"url"`
+	Type string `json:"type,omitempty"`
+}
+
+// Kind returns the payload type of the webhook.
+func (w Webhook) Kind() string {
+	if w.Type != "" {
+		return w.Type
+	}
+	if u, err := url.Parse(w.URL); err == nil && strings.HasSuffix(u.Hostname(), "hooks.slack.com") {
+		return "slack"
+	}
+	return "discord"
+}
+
+func (w Webhook) MarshalJSON() ([]byte, error) {
+	if w.Type == "" {
+		return json.Marshal(w.URL)
+	}
+	type plain Webhook
+	return json.Marshal(plain(w))
+}
+
+func (w *Webhook) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*w = Webhook{URL: s}
+		return nil
+	}
+	type plain Webhook
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("invalid webhook %s", b)
+	}
+	*w = Webhook(p)
+	return nil
+}
+
+// Channel is a named notification destination. Settings are decoded by the
+// notifier registered for Type in the alert package.
+type Channel struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// Route sends alerts whose rule and severity match to Channels. Empty Rules
+// or Severity match everything; an alert matching several routes is sent
+// once to the union of their channels.
+type Route struct {
+	Rules    []string   `json:"rules,omitempty"`
+	Severity []Severity `json:"severity,omitempty"`
+	Channels []string   `json:"channels"`
+}
+
+// Severity of an alert rule.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Valid reports whether s is one of the known severities.
+func (s Severity) Valid() bool {
+	switch s {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// AlertRule fires when Expr holds for every sample during For on a
+// process selected by Match. See rules.ParseExpr for the expression syntax.
+type AlertRule struct {
+	Name        string    `json:"name"`
+	Match       RuleMatch `json:"match,omitempty"`
+	Expr        string    `json:"expr"`
+	For         Duration  `json:"for,omitempty"`
+	Severity    Severity  `json:"severity,omitempty"` // defaults to warning
+	Description string    `json:"description,omitempty"`
+
+	// A firing alert resolves only once Expr has been false this long, so
+	// a process hovering around a threshold does not flap
+	KeepFiringFor Duration `json:"keep_firing_for,omitempty"`
+}
+
+// RuleMatch selects the processes a rule applies to. Empty fields match
+// everything; Comm and Cmdline are regular expressions, User is exact.
+type RuleMatch struct {
+	Comm    string `json:"comm,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
+	User    string `json:"user,omitempty"`
+}
+
+// Duration is a time.Duration stored as a string such as "30s" or "5m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// Plain numbers are seconds
+		var secs float64
+		if err := json.Unmarshal(b, &secs); err != nil {
+			return fmt.Errorf("invalid duration %s", b)
+		}
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+// Validate checks the plain settings of the config. Rules, channels, routes
+// and silences are checked when their packages compile them.
+func (c *SentinelConfig) Validate() []error {
+	var errs []error
+
+	percents := []struct {
+		name  string
+		value float64
+	}{
+		{"cpu_threshold", c.CPUThreshold}, // process CPU is a share of all cores
+		{"mem_threshold", c.MemThreshold},
+		{"sys_mem_threshold", c.SysMemThreshold},
+		{"swap_threshold", c.SwapThreshold},
+	}
+	for _, p := range percents {
+		if p.value < 0 || p.value > 100 {
+			errs = append(errs, fmt.Errorf("%s: %g is not a percentage between 0 and 100", p.name, p.value))
+		}
+	}
+	if c.BuiltinFor != nil && *c.BuiltinFor < 0 {
+		errs = append(errs, fmt.Errorf("builtin_for: negative duration"))
+	}
+	if c.IOReadThresholdMB < 0 {
+		errs = append(errs, fmt.Errorf("io_read_threshold_mb: %g is negative", c.IOReadThresholdMB))
+	}
+	if c.IOWriteThresholdMB < 0 {
+		errs = append(errs, fmt.Errorf("io_write_threshold_mb: %g is negative", c.IOWriteThresholdMB))
+	}
+
+	for name, wh := range c.Webhooks {
+		if wh.URL == "" {
+			errs = append(errs, fmt.Errorf("webhook %q: empty URL", name))
+		}
+	}
+	if c.ActiveWebhook != "" {
+		if _, ok := c.Webhooks[c.ActiveWebhook]; !ok {
+			errs = append(errs, fmt.Errorf("active_webhook: no webhook named %q", c.ActiveWebhook))
+		}
+	}
+
+	switch c.Log.Format {
+	case "", "logfmt", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format: %q is not logfmt or json", c.Log.Format))
+	}
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxAgeDays < 0 || c.Log.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log: rotation limits must not be negative"))
+	}
+	return errs
+}
@@ -0,0 +1,368 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sentinel/model"
+)
+
+// The control socket speaks newline-delimited JSON: one Request per
+// connection, answered by one Response.
+
+// Control commands.
+const (
+	CmdStatus   = "status"
+	CmdReload   = "reload"
+	CmdPause    = "pause"
+	CmdResume   = "resume"
+	CmdSnapshot = "snapshot"
+	CmdStop     = "stop"
+)
+
+type Request struct {
+	Cmd string `json:"cmd"`
+
+	// CmdPause: how long to pause, 0 until resumed
+	For time.Duration `json:"for,omitempty"`
+	// CmdSnapshot: number of processes and sort column ("cpu", "mem", "rss", "read", "write")
+	Limit int    `json:"limit,omitempty"`
+	Sort  string `json:"sort,omitempty"`
+}
+
+type Response struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Status is the answer to CmdStatus.
+type Status struct {
+	PID      int           `json:"pid"`
+	Started  time.Time     `json:"started"`
+	Interval time.Duration `json:"interval"`
+
+	Ticks    uint64        `json:"ticks"`
+	LastTick time.Time     `json:"last_tick"`
+	LastScan time.Duration `json:"last_scan"`
+	Procs    int           `json:"procs"`
+
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"paused_until,omitempty"` // zero: until resumed
+
+	ConfigVersion int       `json:"config_version"`
+	ConfigLoaded  time.Time `json:"config_loaded"`
+	ConfigError   string    `json:"config_error,omitempty"` // why the file on disk is not in use
+	Rules         int       `json:"rules"`
+	Channels      []string  `json:"channels"`
+
+	Active []ActiveAlert `json:"active"`
+}
+
+// ActiveAlert is a pending or firing alert.
+type ActiveAlert struct {
+	Rule    string    `json:"rule"`
+	State   string    `json:"state"`
+	Pid     int       `json:"pid,omitempty"`
+	Comm    string    `json:"comm,omitempty"`
+	Since   time.Time `json:"since"`
+	FiredAt time.Time `json:"fired_at,omitempty"`
+}
+
+// ProcSummary is one process of a CmdSnapshot answer.
+type ProcSummary struct {
+	Pid      int     `json:"pid"`
+	User     string  `json:"user"`
+	Comm     string  `json:"comm"`
+	Cmd      string  `json:"cmd"`
+	CPU      float64 `json:"cpu"`
+	PMem     float64 `json:"mem"`
+	RSSKB    int64   `json:"rss_kb"`
+	ReadBps  float64 `json:"read_bps"`
+	WriteBps float64 `json:"write_bps"`
+	Threads  int64   `json:"threads"`
+}
+
+// SnapshotInfo is the answer to CmdSnapshot.
+type SnapshotInfo struct {
+	Time    time.Time     `json:"time"`
+	CPU     float64       `json:"cpu"`
+	MemUsed float64       `json:"mem_used"`
+	Loads   [3]float64    `json:"loads"`
+	Tasks   int           `json:"tasks"`
+	Procs   []ProcSummary `json:"procs"`
+}
+
+// SocketPath returns the location of the control socket.
+func SocketPath() string {
+	return filepath.Join(RuntimeDir(), "daemon.sock")
+}
+
+// serveControl accepts control connections until ctx is done.
+func (d *Daemon) serveControl(ctx context.Context, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// A socket left behind by a crashed daemon blocks Listen
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use", path)
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	os.Chmod(path, 0o600)
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			d.logger.Warn("control socket accept failed", "err", err)
+			continue
+		}
+		go d.handleControl(ctx, conn)
+	}
+}
+
+func (d *Daemon) handleControl(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		writeResponse(conn, nil, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	d.logger.Debug("control request", "cmd", req.Cmd)
+
+	var data any
+	var err error
+	switch req.Cmd {
+	case CmdStatus:
+		data = d.status()
+	case CmdReload:
+		err = d.requestReload(ctx, "control socket")
+	case CmdPause:
+		d.pause(req.For)
+	case CmdResume:
+		d.resume()
+	case CmdSnapshot:
+		data, err = d.snapshotInfo(req.Limit, req.Sort)
+	case CmdStop:
+		d.logger.Info("stop requested over control socket")
+		d.stop()
+	default:
+		err = fmt.Errorf("unknown command %q", req.Cmd)
+	}
+	writeResponse(conn, data, err)
+}
+
+func writeResponse(conn net.Conn, data any, err error) {
+	resp := Response{OK: err == nil}
+	if err != nil {
+		resp.Error = err.Error()
+	} else if data != nil {
+		resp.Data, err = json.Marshal(data)
+		if err != nil {
+			resp = Response{Error: err.Error()}
+		}
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// requestReload asks the main loop to reload the config and waits for it.
+func (d *Daemon) requestReload(ctx context.Context, trigger string) error {
+	reply := make(chan error, 1)
+	select {
+	case d.reloadReq <- reloadRequest{trigger: trigger, reply: reply}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Daemon) pause(dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.paused = true
+	d.pausedUntil = time.Time{}
+	if dur > 0 {
+		d.pausedUntil = time.Now().Add(dur)
+	}
+	if d.pausedUntil.IsZero() {
+		d.logger.Info("alerting paused until resumed")
+	} else {
+		d.logger.Info("alerting paused", "until", d.pausedUntil)
+	}
+}
+
+func (d *Daemon) resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.paused {
+		d.logger.Info("alerting resumed")
+	}
+	d.paused = false
+	d.pausedUntil = time.Time{}
+}
+
+// isPaused reports whether notifications are paused, ending expired pauses.
+func (d *Daemon) isPaused(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.paused && !d.pausedUntil.IsZero() && !now.Before(d.pausedUntil) {
+		d.paused = false
+		d.pausedUntil = time.Time{}
+		d.logger.Info("alerting resumed, pause expired")
+	}
+	return d.paused
+}
+
+func (d *Daemon) status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := d.st
+	st.PID = os.Getpid()
+	st.Interval = d.interval
+	st.Paused = d.paused
+	st.PausedUntil = d.pausedUntil
+	st.Active = append([]ActiveAlert(nil), d.st.Active...)
+	return st
+}
+
+// updateStatus records the outcome of one tick for CmdStatus.
+func (d *Daemon) updateStatus(scan time.Duration, at time.Time, procs int) {
+	active := d.evaluator.Active()
+	out := make([]ActiveAlert, 0, len(active))
+	for _, a := range active {
+		out = append(out, ActiveAlert{
+			Rule:    a.Rule.Name,
+			State:   a.State.String(),
+			Pid:     a.Proc.Pid,
+			Comm:    a.Proc.Comm,
+			Since:   a.Since,
+			FiredAt: a.FiredAt,
+		})
+	}
+	for rule, st := range d.sys {
+		state := "firing"
+		if st.firedAt.IsZero() {
+			state = "pending"
+		}
+		out = append(out, ActiveAlert{Rule: rule, State: state, Since: st.since, FiredAt: st.firedAt})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.st.Ticks++
+	d.st.LastTick = at
+	d.st.LastScan = scan
+	d.st.Procs = procs
+	d.st.Active = out
+}
+
+// snapshotInfo summarizes the latest sample, top processes first.
+func (d *Daemon) snapshotInfo(limit int, sortBy string) (SnapshotInfo, error) {
+	snap := d.sampler.Latest()
+	if snap == nil {
+		return SnapshotInfo{}, errors.New("no sample yet")
+	}
+
+	sorter := model.NewSorter()
+	switch sortBy {
+	case "", "cpu":
+	case "mem":
+		sorter.Column = model.SortByMEM
+	case "rss":
+		sorter.Column = model.SortByRSS
+	case "read":
+		sorter.Column = model.SortByREAD
+	case "write":
+		sorter.Column = model.SortByWRITE
+	default:
+		return SnapshotInfo{}, fmt.Errorf("unknown sort column %q", sortBy)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	records := append([]model.ProcRec(nil), snap.Records...)
+	sorter.Sort(records)
+	records = records[:min(limit, len(records))]
+
+	info := SnapshotInfo{
+		Time:    snap.Time,
+		CPU:     snap.CPU.Busy,
+		MemUsed: snap.Mem.UsedPercent(),
+		Loads:   snap.Loads,
+		Tasks:   snap.Tasks,
+	}
+	for _, r := range records {
+		info.Procs = append(info.Procs, ProcSummary{
+			Pid:      r.Pid,
+			User:     r.User,
+			Comm:     r.Comm,
+			Cmd:      r.Cmd,
+			CPU:      r.CPU,
+			PMem:     r.PMem,
+			RSSKB:    r.RSSKB,
+			ReadBps:  r.ReadBps,
+			WriteBps: r.WriteBps,
+			Threads:  r.Threads,
+		})
+	}
+	return info, nil
+}
+
+// Call sends one request to the daemon's control socket and decodes the
+// answer's data into out, if not nil.
+func Call(req Request, out any) error {
+	conn, err := net.DialTimeout("unix", SocketPath(), 2*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("reading answer: %w", err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	if out != nil && len(resp.Data) > 0 {
+		return json.Unmarshal(resp.Data, out)
+	}
+	return nil
+}
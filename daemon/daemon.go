@@ -2,138 +2,534 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"sentinel/alert"
 	"sentinel/config"
-	"sentinel/model"
+	"sentinel/history"
+	"sentinel/internal/rotfile"
 	"sentinel/monitor"
 	"sentinel/proc"
-
-	"github.com/fsnotify/fsnotify"
+	"sentinel/rules"
+	"sentinel/silence"
 )
 
 type Daemon struct {
-	engine     *monitor.Engine
-	cfg        *config.SentinelConfig
-	logger     *log.Logger
-	interval   time.Duration
-	hz         int
-	lastAlerts map[int]time.Time
+	sampler  *monitor.Sampler
+	cfg      *config.SentinelConfig // only touched by the main loop
+	logger   *slog.Logger
+	logLevel slog.LevelVar // follows the config on reload
+	logFile  *rotfile.File // nil if it could not be opened
+	logFmt   string        // format the logger was created with
+	interval time.Duration
+	hz       int
+	host     string
+	sys      map[string]*sysAlert // system alerts pending or firing, by rule
+
+	evaluator  *rules.Evaluator
+	router     atomic.Pointer[alert.Router] // read by the outbox worker
+	silences   *silence.Set
+	suppressed map[string]alert.Alert // firing notifications held back, by alertKey
+	outbox     *alert.Outbox          // nil if it could not be opened
+	history    *history.Writer        // nil if it could not be opened
+
+	reloadReq chan reloadRequest // from the control socket and the config watcher
+	stop      context.CancelFunc
+	sdStatus  string // last status text sent to systemd
+
+	mu          sync.Mutex // guards the fields below, read by the control socket
+	st          Status
+	paused      bool
+	pausedUntil time.Time
 }
 
-func New(interval time.Duration, hz int, logger *log.Logger) *Daemon {
-	cfg, _ := config.LoadConfig()
+// notifyTimeout bounds the delivery of one tick's alerts.
+const notifyTimeout = 10 * time.Second
 
-	return &Daemon{
-		engine:     monitor.NewEngine(),
+// New creates a daemon sampling every interval. It logs to the daemon log
+// file and, if console is not nil, to console as well.
+func New(interval time.Duration, hz int, console io.Writer) *Daemon {
+	// Without a readable config start on the defaults; the file is left
+	// alone and picked up once it is fixed
+	cfg, cfgErr := config.LoadConfig()
+	if cfgErr != nil {
+		cfg = config.Default()
+	}
+	host, _ := os.Hostname()
+
+	d := &Daemon{
+		sampler:    monitor.NewSampler(interval),
 		cfg:        cfg,
-		logger:     logger,
 		interval:   interval,
 		hz:         hz,
-		lastAlerts: make(map[int]time.Time),
+		host:       host,
+		sys:        make(map[string]*sysAlert),
+		suppressed: make(map[string]alert.Alert),
+		evaluator:  rules.NewEvaluator(nil),
+		reloadReq:  make(chan reloadRequest),
+		st:         Status{Started: time.Now()},
+	}
+
+	var err error
+	d.logger, d.logFile, err = openLog(cfg.Log, LogPath(config.Dir()), console, &d.logLevel)
+	d.logFmt = logFormat(cfg.Log.Format)
+	if err != nil {
+		d.logger.Error("log file unavailable", "path", LogPath(config.Dir()), "err", err)
+	}
+	if cfgErr != nil {
+		d.setConfigError(cfgErr)
+		d.logger.Error("invalid config, running on defaults until it is fixed", "err", cfgErr)
+	}
+
+	d.outbox, err = alert.OpenOutbox(alert.OutboxDir(config.Dir()), d.logger)
+	if err != nil {
+		d.logger.Warn("outbox unavailable, alerts are sent without retries", "err", err)
 	}
+	d.history, err = history.OpenWriter(history.Path(config.Dir()))
+	if err != nil {
+		d.logger.Warn("alert history unavailable", "err", err)
+	}
+	return d
 }
 
 func (d *Daemon) Run(ctx context.Context) error {
-	go d.watchConfig()
+	ctx, d.stop = context.WithCancel(ctx)
+	defer d.stop()
 
-	ticker := time.NewTicker(d.interval)
-	defer ticker.Stop()
+	d.logger.Info("daemon started", "pid", os.Getpid(), "interval", d.interval, "config", config.ConfigPath())
+	defer func() {
+		sdNotify("STOPPING=1")
+		d.logger.Info("daemon stopped")
+		if d.logFile != nil {
+			d.logFile.Close()
+		}
+	}()
 
-	prevTotal := int64(proc.ReadTotalCPUTime())
-	memTotal := proc.ReadMemTotalKB()
+	go d.watchConfig(ctx)
+	go func() {
+		if err := d.serveControl(ctx, SocketPath()); err != nil {
+			d.logger.Error("control socket unavailable", "path", SocketPath(), "err", err)
+		}
+	}()
+
+	if d.history != nil {
+		defer d.history.Close()
+	}
+
+	// Start on whatever is usable, later reloads are all or nothing
+	cc, errs := compileConfig(d.cfg)
+	for _, err := range errs {
+		d.logger.Warn("invalid config entry skipped", "err", err)
+	}
+	d.apply(d.cfg, cc)
+	if len(errs) > 0 {
+		d.setConfigError(errors.Join(errs...))
+	}
+
+	if d.outbox != nil {
+		go d.outbox.Run(ctx, d.channel, d.delivered)
+	}
+
+	snaps, unsubscribe := d.sampler.Subscribe()
+	defer unsubscribe()
+
+	go d.sampler.Run(ctx)
+
+	if err := sdNotify("READY=1"); err != nil {
+		d.logger.Warn("failed to notify systemd", "err", err)
+	}
+
+	// Ping the systemd watchdog from the main loop, twice per timeout, so a
+	// hung loop gets the service restarted
+	var watchdog <-chan time.Time
+	timeout := watchdogTimeout()
+	if timeout > 0 {
+		t := time.NewTicker(timeout / 2)
+		defer t.Stop()
+		watchdog = t.C
+		d.logger.Info("systemd watchdog enabled", "timeout", timeout)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
-		case <-ticker.C:
-			tasks, running := d.engine.Collector.Scan()
+		case <-watchdog:
+			d.pingWatchdog(timeout)
 
-			curTotal := int64(proc.ReadTotalCPUTime())
-			sysDelta := int64(1)
-			if curTotal > prevTotal {
-				sysDelta = curTotal - prevTotal
+		case snap, ok := <-snaps:
+			if !ok {
+				return ctx.Err()
 			}
+			d.handleSnapshot(snap)
 
-			for i := range d.engine.Collector.Records {
-				r := &d.engine.Collector.Records[i]
-				if !r.Alive {
-					continue
-				}
-
-				if r.PrevProcTime == 0 {
-					r.CPU = 0
-				} else {
-					procDelta := uint64(0)
-					if r.CurProcTime > r.PrevProcTime {
-						procDelta = r.CurProcTime - r.PrevProcTime
-					}
-					r.CPU = float64(procDelta) * 100.0 / float64(sysDelta)
-				}
-
-				if memTotal > 0 {
-					r.PMem = float64(r.RSSKB) * 100.0 / float64(memTotal)
-				}
-
-				r.PrevProcTime = r.CurProcTime
-
-				d.checkAlerts(r)
-			}
+		case req := <-d.reloadReq:
+			req.reply <- d.reload(req.trigger)
+		}
+	}
+}
+
+// handleSnapshot evaluates alert rules and system thresholds against one
+// collection cycle.
+func (d *Daemon) handleSnapshot(snap *monitor.Snapshot) {
+	// Resurfaced firings go first: this tick may resolve them
+	alerts := d.resurface(snap.Time)
+	for _, ev := range d.evaluator.Step(snap.Time, snap.Records) {
+		alerts = append(alerts, d.ruleAlert(ev))
+	}
+	alerts = append(alerts, d.checkSystemAlerts(snap.Time, snap.Mem)...)
+
+	d.notify(alerts)
+	d.updateStatus(snap.ScanDuration, snap.Time, len(snap.Records))
+	d.notifyStatus()
+	d.logger.Debug("tick", "procs", len(snap.Records), "scan", snap.ScanDuration, "alerts", len(alerts))
+}
+
+// notify records one tick's alerts and queues those not covered by a pause
+// or silence for delivery to their channels. Without an outbox they are
+// sent directly and failures are only logged. The resolution of an alert
+// whose firing was held back is not sent either: nobody was told it fired.
+func (d *Daemon) notify(alerts []alert.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	router := d.router.Load()
+	now := time.Now()
+	paused := d.isPaused(now)
+
+	var send []alert.Alert
+	for i := range alerts {
+		a := &alerts[i]
+		rec := history.Record{Time: a.Time, Kind: history.KindAlert, Alert: a}
+		key := alertKey(a)
+		_, held := d.suppressed[key]
+		switch {
+		case a.Resolved && held:
+			rec.Silenced = "firing suppressed"
+		case paused:
+			rec.Silenced = "pause"
+		default:
+			rec.Silenced = d.silences.Silenced(a, now)
+		}
 
-			prevTotal = curTotal
-			memTotal = proc.ReadMemTotalKB()
+		switch {
+		case a.Resolved:
+			delete(d.suppressed, key)
+		case rec.Silenced != "":
+			d.suppressed[key] = *a
+		default:
+			delete(d.suppressed, key)
+		}
 
-			_ = tasks
-			_ = running
+		if rec.Silenced != "" {
+			d.logger.Info("alert suppressed", alertAttrs(a, "by", rec.Silenced)...)
+		} else {
+			d.logger.Info("alert", alertAttrs(a)...)
+			rec.Channels = router.Route(*a)
+			send = append(send, *a)
 		}
+		d.record(rec)
+	}
+	alerts = send
+	if len(alerts) == 0 {
+		return
+	}
+
+	if d.outbox != nil {
+		if err := d.outbox.Enqueue(router.Group(alerts), now); err != nil {
+			d.logger.Error("failed to queue alerts", "err", err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	errs := router.Dispatch(ctx, alerts)
+	for name, batch := range router.Group(alerts) {
+		err := errs[name]
+		if err != nil {
+			d.logger.Warn("notification failed", "channel", name, "alerts", len(batch), "err", err)
+		}
+		d.record(deliveryRecord(name, batch, 1, err, err != nil))
 	}
 }
 
-func (d *Daemon) checkAlerts(r *model.ProcRec) {
-	now := time.Now()
+// alertKey identifies the alert of one rule on one process, or of a system
+// rule, across its firing and resolved notifications.
+func alertKey(a *alert.Alert) string {
+	return fmt.Sprintf("%s/%d", a.Rule, a.Pid)
+}
+
+// resurface returns a fresh firing notification for every alert whose
+// firing was suppressed, is still firing and is no longer covered by a
+// pause or silence. Entries of alerts that stopped without a resolved
+// event, e.g. because their rule was removed, are dropped.
+func (d *Daemon) resurface(now time.Time) []alert.Alert {
+	if len(d.suppressed) == 0 || d.isPaused(now) {
+		return nil
+	}
 
-	if t, ok := d.lastAlerts[r.Pid]; ok {
-		if now.Sub(t) < 60*time.Second {
-			return
+	firing := make(map[string]rules.Alert)
+	for _, a := range d.evaluator.Active() {
+		if a.State == rules.StateFiring {
+			firing[fmt.Sprintf("%s/%d", a.Rule.Name, a.Proc.Pid)] = a
 		}
 	}
 
-	if r.CPU >= d.cfg.CPUThreshold {
-		alert.SendDiscord(
-			d.cfg.Webhooks[d.cfg.ActiveWebhook],
-			fmt.Sprintf("⚠ High CPU: PID %d (%s)", r.Pid, r.Cmd),
-		)
-		d.lastAlerts[r.Pid] = now
+	var out []alert.Alert
+	for key, held := range d.suppressed {
+		var a alert.Alert
+		if active, ok := firing[key]; ok {
+			a = d.ruleAlert(rules.Event{Alert: active})
+		} else if st := d.sys[held.Rule]; held.Pid == 0 && st != nil && !st.firedAt.IsZero() {
+			a = held
+		} else {
+			delete(d.suppressed, key)
+			continue
+		}
+		if d.silences.Silenced(&a, now) != "" {
+			continue
+		}
+		a.ID = alert.NewID(now)
+		a.Time = now
+		out = append(out, a)
 	}
+	return out
+}
 
-	if r.PMem >= d.cfg.MemThreshold {
-		alert.SendDiscord(
-			d.cfg.Webhooks[d.cfg.ActiveWebhook],
-			fmt.Sprintf("⚠ High Memory: PID %d (%s)", r.Pid, r.Cmd),
-		)
-		d.lastAlerts[r.Pid] = now
+// channel resolves a channel name for the outbox worker.
+func (d *Daemon) channel(name string) (alert.Notifier, bool) {
+	return d.router.Load().Notifier(name)
+}
+
+// delivered is called by the outbox worker after each delivery attempt.
+func (d *Daemon) delivered(e *alert.OutboxEntry, err error) {
+	gaveUp := err != nil && e.NextAttempt.IsZero()
+	d.record(deliveryRecord(e.Channel, e.Alerts, e.Attempts, err, gaveUp))
+
+	switch {
+	case err == nil:
+		d.logger.Debug("notification delivered", "channel", e.Channel, "alerts", len(e.Alerts), "attempt", e.Attempts)
+	case gaveUp:
+		d.logger.Error("notification failed, giving up", "channel", e.Channel, "attempts", e.Attempts, "err", err)
+	default:
+		d.logger.Warn("notification failed", "channel", e.Channel, "attempt", e.Attempts,
+			"retry_at", e.NextAttempt.Format(time.TimeOnly), "err", err)
 	}
 }
 
-func (d *Daemon) watchConfig() {
-	w, _ := fsnotify.NewWatcher()
-	w.Add(config.ConfigPath())
+func deliveryRecord(channel string, alerts []alert.Alert, attempts int, err error, gaveUp bool) history.Record {
+	r := history.Record{
+		Time:     time.Now(),
+		Kind:     history.KindDelivery,
+		Channel:  channel,
+		OK:       err == nil,
+		Attempts: attempts,
+		GaveUp:   gaveUp,
+	}
+	for _, a := range alerts {
+		r.IDs = append(r.IDs, a.ID)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
 
-	for {
-		select {
-		case e := <-w.Events:
-			if e.Op&fsnotify.Write == fsnotify.Write {
-				cfg, err := config.LoadConfig()
-				if err == nil {
-					d.cfg = cfg
-					d.logger.Println("config reloaded")
-				}
-			}
+// record appends to the alert history, if available.
+func (d *Daemon) record(r history.Record) {
+	if d.history == nil {
+		return
+	}
+	if err := d.history.Append(r); err != nil {
+		d.logger.Error("failed to write alert history", "err", err)
+	}
+}
+
+// alertAttrs returns the log attributes describing an alert, followed by
+// extra.
+func alertAttrs(a *alert.Alert, extra ...any) []any {
+	attrs := []any{"id", a.ID, "rule", a.Rule, "state", a.State(), "severity", a.Severity}
+	if a.Pid != 0 {
+		attrs = append(attrs, "pid", a.Pid, "comm", a.Comm)
+	}
+	return append(attrs, extra...)
+}
+
+// ruleAlert converts a lifecycle event into a notification.
+func (d *Daemon) ruleAlert(ev rules.Event) alert.Alert {
+	r := &ev.Proc
+	a := alert.Alert{
+		Rule:     ev.Rule.Name,
+		Severity: ev.Rule.Severity,
+		Resolved: ev.State == rules.StateResolved,
+		Time:     ev.FiredAt,
+		Host:     d.host,
+		Title:    ev.Rule.Description,
+		Text:     formatEvent(ev),
+		Pid:      r.Pid,
+		Comm:     r.Comm,
+		Cmd:      r.Cmd,
+		User:     r.User,
+		CPU:      r.CPU,
+		PMem:     r.PMem,
+		RSSKB:    r.RSSKB,
+		Expr:     ev.Rule.Expr.String(),
+		Duration: ev.FiredAt.Sub(ev.Since),
+		Exited:   ev.Exited,
+	}
+	if a.Title == "" {
+		a.Title = ev.Rule.Name
+	}
+	if metric, _, threshold, ok := ev.Rule.Expr.Threshold(); ok {
+		a.Metric = metric
+		a.Value = rules.Metrics{Rec: r}.Get(metric)
+		a.Threshold = threshold
+	}
+	if a.Resolved {
+		a.Time = ev.ResolvedAt
+		a.Duration = ev.ResolvedAt.Sub(ev.FiredAt)
+	}
+	a.ID = alert.NewID(a.Time)
+	return a
+}
+
+// formatEvent renders the alert message for a lifecycle event.
+func formatEvent(ev rules.Event) string {
+	title := ev.Rule.Description
+	if title == "" {
+		title = ev.Rule.Name
+	}
+
+	r := &ev.Proc
+	cmd := r.Cmd
+	if cmd == "" {
+		cmd = r.Comm
+	}
+
+	if ev.State == rules.StateResolved {
+		reason := "recovered"
+		if ev.Exited {
+			reason = "process exited"
+		}
+		return fmt.Sprintf("✅ Resolved %s: PID %d (%s) after %s, %s",
+			title, r.Pid, cmd, ev.ResolvedAt.Sub(ev.FiredAt).Round(time.Second), reason)
+	}
+
+	msg := fmt.Sprintf("⚠ %s: PID %d (%s) [%s] cpu=%.1f%% mem=%.1f%%",
+		title, r.Pid, cmd, ev.Rule.Expr, r.CPU, r.PMem)
+	if ev.Rule.For > 0 {
+		msg += fmt.Sprintf(" for %s", ev.FiredAt.Sub(ev.Since).Round(time.Second))
+	}
+	return msg
+}
+
+// checkSystemAlerts compares host memory and swap usage against the
+// system-wide thresholds. Used memory is total minus MemAvailable, so
+// reclaimable page cache does not trigger alerts.
+func (d *Daemon) checkSystemAlerts(now time.Time, mem proc.MemInfo) []alert.Alert {
+	if mem.MemTotalKB == 0 {
+		return nil
+	}
+
+	var out []alert.Alert
+	if a, ok := d.setSystemAlert(now, "system-mem", "High System Memory",
+		"sys_mem", mem.UsedPercent(), d.cfg.SysMemThreshold,
+		d.cfg.SysMemThreshold > 0 && mem.UsedPercent() >= d.cfg.SysMemThreshold,
+		fmt.Sprintf("%.1f%% used (%d KB available of %d KB)",
+			mem.UsedPercent(), mem.MemAvailableKB, mem.MemTotalKB),
+	); ok {
+		out = append(out, a)
+	}
+
+	if a, ok := d.setSystemAlert(now, "system-swap", "High Swap Usage",
+		"swap", mem.SwapUsedPercent(), d.cfg.SwapThreshold,
+		d.cfg.SwapThreshold > 0 && mem.SwapTotalKB > 0 && mem.SwapUsedPercent() >= d.cfg.SwapThreshold,
+		fmt.Sprintf("%.1f%% used (%d KB of %d KB)",
+			mem.SwapUsedPercent(), mem.SwapUsedKB(), mem.SwapTotalKB),
+	); ok {
+		out = append(out, a)
+	}
+	return out
+}
+
+// sysAlert is the state of a system alert, with the lifecycle of the
+// built-in process rules: pending until its threshold has held for
+// builtin_for, then firing until it has been clear for the keep-firing
+// period. A single sample below the threshold resets a pending alert.
+type sysAlert struct {
+	since     time.Time // first sample of the current run above the threshold
+	firedAt   time.Time // zero while pending
+	clearedAt time.Time // first sample below the threshold while firing
+}
+
+// setSystemAlert advances the lifecycle of a system alert by one sample and
+// returns a notification when it starts firing and when it resolves.
+func (d *Daemon) setSystemAlert(now time.Time, rule, title, metric string, value, threshold float64,
+	holds bool, detail string) (alert.Alert, bool) {
+	forDur, keepFiring := rules.BuiltinTiming(d.cfg)
+	st := d.sys[rule]
+
+	var firing bool
+	switch {
+	case holds && st == nil:
+		st = &sysAlert{since: now}
+		d.sys[rule] = st
+		if forDur > 0 {
+			return alert.Alert{}, false
+		}
+		firing = true
+	case holds:
+		st.clearedAt = time.Time{}
+		if !st.firedAt.IsZero() || now.Sub(st.since) < forDur {
+			return alert.Alert{}, false
 		}
+		firing = true
+	case st == nil:
+		return alert.Alert{}, false
+	case st.firedAt.IsZero():
+		delete(d.sys, rule)
+		return alert.Alert{}, false
+	default:
+		if st.clearedAt.IsZero() {
+			st.clearedAt = now
+		}
+		if now.Sub(st.clearedAt) < keepFiring {
+			return alert.Alert{}, false
+		}
+	}
+
+	a := alert.Alert{
+		ID:       alert.NewID(now),
+		Rule:     rule,
+		Severity: config.SeverityWarning,
+		Resolved: !firing,
+		Time:     now,
+		Host:     d.host,
+		Title:    title,
+
+		Metric:    metric,
+		Value:     value,
+		Threshold: threshold,
+		Expr:      fmt.Sprintf("%s >= %g", metric, threshold),
+	}
+	if firing {
+		a.Text = "⚠ " + title + ": " + detail
+		a.Duration = now.Sub(st.since)
+		st.firedAt = now
+	} else {
+		a.Text = "✅ Resolved " + title + ": " + detail
+		a.Duration = now.Sub(st.firedAt)
+		delete(d.sys, rule)
 	}
+	return a, true
 }
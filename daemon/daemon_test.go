@@ -0,0 +1,184 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sentinel/alert"
+	"sentinel/config"
+	"sentinel/model"
+	"sentinel/monitor"
+	"sentinel/proc"
+	"sentinel/rules"
+	"sentinel/silence"
+)
+
+// memSample returns a host with used percent of its memory in use.
+func memSample(used int64) proc.MemInfo {
+	return proc.MemInfo{MemTotalKB: 1000, MemFreeKB: 1000 - used*10, MemAvailableKB: 1000 - used*10}
+}
+
+func TestSystemAlertLifecycle(t *testing.T) {
+	cfg := config.Default()
+	forDur := config.Duration(30 * time.Second)
+	cfg.BuiltinFor = &forDur
+	d := &Daemon{cfg: cfg, host: "box", sys: make(map[string]*sysAlert)}
+	t0 := time.Unix(1_700_000_000, 0)
+
+	var got []string
+	sample := func(sec int, used int64) {
+		for _, a := range d.checkSystemAlerts(t0.Add(time.Duration(sec)*time.Second), memSample(used)) {
+			state := "firing"
+			if a.Resolved {
+				state = "resolved"
+			}
+			got = append(got, a.Rule+" "+state)
+		}
+	}
+
+	// Alternating around the threshold never holds for 30s
+	for sec := 0; sec < 120; sec++ {
+		sample(sec, int64(85+10*(sec%2)))
+	}
+	if len(got) != 0 {
+		t.Fatalf("alternating samples sent %v", got)
+	}
+
+	// Fires once the threshold has held for 30s
+	for sec := 200; sec <= 230; sec++ {
+		sample(sec, 95)
+	}
+	if len(got) != 1 || got[0] != "system-mem firing" {
+		t.Fatalf("sent %v, want one firing alert", got)
+	}
+
+	// Alternating again keeps it firing without resolving or refiring
+	for sec := 231; sec < 400; sec++ {
+		sample(sec, int64(85+10*(sec%2)))
+	}
+	if len(got) != 1 {
+		t.Fatalf("alternating samples while firing sent %v", got[1:])
+	}
+
+	// Resolves once it has been clear for the keep-firing period
+	for sec := 400; sec <= 460; sec++ {
+		sample(sec, 50)
+	}
+	if len(got) != 2 || got[1] != "system-mem resolved" {
+		t.Fatalf("sent %v, want the alert resolved", got)
+	}
+	if len(d.sys) != 0 {
+		t.Errorf("state left after resolving: %+v", d.sys)
+	}
+}
+
+// notifyDaemon returns a daemon with one rule, "hot", sending its alerts
+// to a webhook that records them as "rule/pid state".
+func notifyDaemon(t *testing.T) (*Daemon, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alert.Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		mu.Lock()
+		got = append(got, fmt.Sprintf("%s/%d %s", a.Rule, a.Pid, a.State()))
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	settings, _ := json.Marshal(map[string]string{"url": srv.URL})
+	router, errs := alert.NewRouter(&config.SentinelConfig{
+		Channels: []config.Channel{{Name: "hook", Type: "webhook", Settings: settings}},
+	})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	hot, err := rules.Compile(config.AlertRule{Name: "hot", Expr: "cpu > 90"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Daemon{
+		cfg:        &config.SentinelConfig{},
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		sys:        make(map[string]*sysAlert),
+		suppressed: make(map[string]alert.Alert),
+		evaluator:  rules.NewEvaluator([]*rules.Rule{hot}),
+	}
+	d.router.Store(router)
+	return d, &got
+}
+
+// silenceAll returns a set silencing every alert for the next hour.
+func silenceAll(t *testing.T) *silence.Set {
+	t.Helper()
+	now := time.Now()
+	set, errs := silence.Compile(&config.SentinelConfig{Silences: []config.Silence{
+		{ID: "s1", Start: now.Add(-time.Minute), End: now.Add(time.Hour)},
+	}})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return set
+}
+
+func worker(pid int, cpu float64) model.ProcRec {
+	return model.ProcRec{Pid: pid, StartTime: uint64(pid), Comm: "worker", CPU: cpu, Alive: true}
+}
+
+func TestSuppressedAlerts(t *testing.T) {
+	d, got := notifyDaemon(t)
+	tick := func(records ...model.ProcRec) {
+		d.handleSnapshot(&monitor.Snapshot{Time: time.Now(), Records: records})
+	}
+
+	// A firing held back by a silence resurfaces once the silence ends
+	// and then resolves normally
+	d.silences = silenceAll(t)
+	tick(worker(1, 95))
+	if len(*got) != 0 || len(d.suppressed) != 1 {
+		t.Fatalf("silenced firing: sent %v, %d held", *got, len(d.suppressed))
+	}
+	d.silences = nil
+	tick(worker(1, 95))
+	tick(worker(1, 10))
+	if want := "hot/1 firing,hot/1 resolved"; strings.Join(*got, ",") != want {
+		t.Fatalf("sent %v, want %s", *got, want)
+	}
+	if len(d.suppressed) != 0 {
+		t.Errorf("still held: %v", d.suppressed)
+	}
+
+	// The resolution of a held firing is not sent, even once the
+	// silence has ended
+	*got = nil
+	d.silences = silenceAll(t)
+	tick(worker(2, 95))
+	tick(worker(2, 10))
+	d.silences = nil
+	tick(worker(2, 10))
+	if len(*got) != 0 || len(d.suppressed) != 0 {
+		t.Fatalf("held firing resolved: sent %v, %d held", *got, len(d.suppressed))
+	}
+
+	// A held firing whose rule was removed is dropped without a word
+	d.silences = silenceAll(t)
+	tick(worker(3, 95))
+	d.evaluator.SetRules(nil)
+	d.silences = nil
+	tick(worker(3, 95))
+	if len(*got) != 0 || len(d.suppressed) != 0 {
+		t.Errorf("orphaned firing: sent %v, %d held", *got, len(d.suppressed))
+	}
+}
@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrRunning is returned by Lock when another daemon holds the lock.
+var ErrRunning = errors.New("daemon already running")
+
+// RuntimeDir returns the directory holding the daemon's lock, PID file and
+// control socket: $XDG_RUNTIME_DIR/sentinel, or a per-user directory in
+// the temp dir when XDG_RUNTIME_DIR is not set.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sentinel")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sentinel-%d", os.Getuid()))
+}
+
+// ensureRuntimeDir creates the runtime dir and checks that it is a private
+// directory of ours, not something planted in a shared temp dir.
+func ensureRuntimeDir() (string, error) {
+	dir := RuntimeDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	st, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	sys, ok := st.Sys().(*syscall.Stat_t)
+	if !st.IsDir() || !ok || int(sys.Uid) != os.Getuid() || st.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("runtime dir %s must be a directory owned by uid %d with mode 0700", dir, os.Getuid())
+	}
+	return dir, nil
+}
+
+// PIDPath returns the location of the daemon's PID file.
+func PIDPath() string {
+	return filepath.Join(RuntimeDir(), "daemon.pid")
+}
+
+func lockPath() string {
+	return filepath.Join(RuntimeDir(), "daemon.lock")
+}
+
+// Instance is the lock held by the running daemon.
+type Instance struct {
+	lock *os.File
+}
+
+// Lock makes the calling process the single running daemon: it takes an
+// exclusive flock on the lock file, held until Release or exit, and
+// publishes the PID file. It returns ErrRunning if another daemon holds
+// the lock.
+func Lock() (*Instance, error) {
+	if _, err := ensureRuntimeDir(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(lockPath(), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if pid, err := ReadPID(); err == nil {
+				return nil, fmt.Errorf("%w (pid %d)", ErrRunning, pid)
+			}
+			return nil, ErrRunning
+		}
+		return nil, err
+	}
+
+	if err := writePIDFile(PIDPath(), os.Getpid()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Instance{lock: f}, nil
+}
+
+// Release removes the PID file and drops the lock. The lock file itself
+// stays: removing it would let a new daemon lock a fresh file while an old
+// one still holds the unlinked one.
+func (in *Instance) Release() {
+	os.Remove(PIDPath())
+	in.lock.Close()
+}
+
+// writePIDFile replaces the PID file atomically, so readers never see a
+// partial write.
+func writePIDFile(path string, pid int) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".daemon.pid-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(strconv.Itoa(pid) + "\n")
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// ReadPID returns the PID of the running daemon from the PID file.
+func ReadPID() (int, error) {
+	b, err := os.ReadFile(PIDPath())
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s", PIDPath())
+	}
+	return pid, nil
+}
+
+// Running reports whether a daemon holds the lock, and its PID if the PID
+// file names a live sentinel daemon process.
+func Running() (pid int, running bool) {
+	f, err := os.Open(lockPath())
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return 0, false
+	}
+	if pid, err := ReadPID(); err == nil && IsDaemonProcess(pid) {
+		return pid, true
+	}
+	return 0, true
+}
+
+// IsDaemonProcess reports whether pid is a "sentinel daemon" process run
+// from the same binary as the caller, so a stale PID reused by an
+// unrelated process is never signalled.
+func IsDaemonProcess(pid int) bool {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return false
+	}
+	// An upgraded binary shows up as "/path/sentinel (deleted)"
+	exe = strings.TrimSuffix(exe, " (deleted)")
+
+	self, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+	if exe != self && filepath.Base(exe) != filepath.Base(self) {
+		return false
+	}
+
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return false
+	}
+	args := strings.Split(string(cmdline), "\x00")
+	return len(args) > 1 && args[1] == "daemon"
+}
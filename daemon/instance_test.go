@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate points the runtime dir and home of the daemon at a temp dir.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("HOME", dir)
+	return dir
+}
+
+func TestLock(t *testing.T) {
+	isolate(t)
+	if _, running := Running(); running {
+		t.Fatal("running before Lock")
+	}
+
+	in, err := Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := ReadPID(); err != nil || pid != os.Getpid() {
+		t.Errorf("PID file: %d, %v", pid, err)
+	}
+	if _, running := Running(); !running {
+		t.Error("not running while locked")
+	}
+
+	_, err = Lock()
+	if !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Lock: %v, want ErrRunning", err)
+	}
+	if !strings.Contains(err.Error(), "pid") {
+		t.Errorf("second Lock: %v, want the PID of the holder", err)
+	}
+
+	in.Release()
+	if _, running := Running(); running {
+		t.Error("running after Release")
+	}
+	if _, err := os.Stat(PIDPath()); !os.IsNotExist(err) {
+		t.Errorf("PID file left after Release: %v", err)
+	}
+
+	// The lock can be taken again
+	in, err = Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Release()
+}
+
+func TestRuntimeDirMode(t *testing.T) {
+	dir := isolate(t)
+	shared := filepath.Join(dir, "sentinel")
+	if err := os.Mkdir(shared, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(shared, 0o755)
+	if _, err := Lock(); err == nil || !strings.Contains(err.Error(), "mode 0700") {
+		t.Errorf("Lock in a shared runtime dir: %v", err)
+	}
+}
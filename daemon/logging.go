@@ -0,0 +1,138 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/internal/rotfile"
+)
+
+// Default rotation policy of the daemon log.
+const (
+	defaultLogMaxSize    = 10 << 20
+	defaultLogMaxAge     = 7 * 24 * time.Hour
+	defaultLogMaxBackups = 5
+)
+
+// LogPath returns the location of the daemon log under the sentinel state dir.
+func LogPath(stateDir string) string {
+	return filepath.Join(stateDir, "daemon.log")
+}
+
+// LogFiles returns the daemon log files oldest first: the rotated backups
+// followed by the active file. Missing files are skipped.
+func LogFiles(path string, maxBackups int) []string {
+	if maxBackups <= 0 {
+		maxBackups = defaultLogMaxBackups
+	}
+	return rotfile.Files(path, maxBackups)
+}
+
+// logPolicy returns the rotation policy of the daemon log, with the
+// defaults for the limits left unset.
+func logPolicy(cfg config.LogConfig) rotfile.Policy {
+	p := rotfile.Policy{
+		MaxSize:    int64(cfg.MaxSizeMB) << 20,
+		MaxAge:     time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		MaxBackups: cfg.MaxBackups,
+	}
+	if p.MaxSize <= 0 {
+		p.MaxSize = defaultLogMaxSize
+	}
+	if p.MaxAge <= 0 {
+		p.MaxAge = defaultLogMaxAge
+	}
+	if p.MaxBackups <= 0 {
+		p.MaxBackups = defaultLogMaxBackups
+	}
+	return p
+}
+
+// ParseLevel parses a log level name, empty meaning info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q, want debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// openLog creates the daemon logger. Records go to the rotating log file
+// and to console, if not nil. When the file cannot be opened the logger
+// writes to console only and the error is returned along with it.
+func openLog(cfg config.LogConfig, path string, console io.Writer, level *slog.LevelVar) (*slog.Logger, *rotfile.File, error) {
+	lvl, levelErr := ParseLevel(cfg.Level)
+	level.Set(lvl)
+
+	var w io.Writer = io.Discard
+	if console != nil {
+		w = console
+	}
+	file, err := rotfile.Open(path, logPolicy(cfg), logLineTime)
+	if err == nil {
+		w = file
+		if console != nil {
+			w = io.MultiWriter(file, console)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch cfg.Format {
+	case "", "logfmt":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		h = slog.NewTextHandler(w, opts)
+		if levelErr == nil {
+			levelErr = fmt.Errorf("invalid log format %q, want logfmt or json", cfg.Format)
+		}
+	}
+	logger := slog.New(h)
+
+	if levelErr != nil {
+		logger.Warn("log settings", "err", levelErr)
+	}
+	if err != nil {
+		return logger, nil, err
+	}
+	return logger, file, nil
+}
+
+// logFormat returns the format openLog writes for the configured one.
+func logFormat(s string) string {
+	if s == "json" {
+		return s
+	}
+	return "logfmt"
+}
+
+// logLineTime reads the time of a log record, in either format.
+func logLineTime(b []byte) (time.Time, bool) {
+	line := string(b)
+
+	var ts string
+	if strings.HasPrefix(line, "{") {
+		var rec struct {
+			Time string `json:"time"`
+		}
+		if json.Unmarshal(b, &rec) != nil {
+			return time.Time{}, false
+		}
+		ts = rec.Time
+	} else if rest, ok := strings.CutPrefix(line, "time="); ok {
+		ts, _, _ = strings.Cut(rest, " ")
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	return t, err == nil
+}
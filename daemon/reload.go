@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sentinel/alert"
+	"sentinel/config"
+	"sentinel/rules"
+	"sentinel/silence"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadRequest asks the main loop to reload the config. The result is
+// sent on reply.
+type reloadRequest struct {
+	trigger string
+	reply   chan error
+}
+
+// compiledConfig is everything the daemon builds from a config.
+type compiledConfig struct {
+	rules    []*rules.Rule
+	router   *alert.Router
+	silences *silence.Set
+	logLevel slog.Level
+}
+
+// compileConfig validates cfg and builds its rules, routes and silences.
+// Invalid entries are left out of the result and reported in errs.
+func compileConfig(cfg *config.SentinelConfig) (*compiledConfig, []error) {
+	cc := &compiledConfig{}
+	errs := cfg.Validate()
+
+	var more []error
+	cc.rules, more = rules.FromConfig(cfg)
+	errs = append(errs, more...)
+	cc.router, more = alert.NewRouter(cfg)
+	errs = append(errs, more...)
+	cc.silences, more = silence.Compile(cfg)
+	errs = append(errs, more...)
+
+	var err error
+	if cc.logLevel, err = ParseLevel(cfg.Log.Level); err != nil {
+		errs = append(errs, err)
+	}
+	return cc, errs
+}
+
+// apply switches the daemon to cfg. Only the main loop calls it, so d.cfg
+// needs no locking.
+func (d *Daemon) apply(cfg *config.SentinelConfig, cc *compiledConfig) {
+	d.cfg = cfg
+	d.evaluator.SetRules(cc.rules)
+	d.sampler.SetCountFDs(rules.UseMetric(cc.rules, "fds"))
+	d.router.Store(cc.router)
+	d.silences = cc.silences
+	d.logLevel.Set(cc.logLevel)
+	if d.logFile != nil {
+		d.logFile.SetPolicy(logPolicy(cfg.Log))
+	}
+	if logFormat(cfg.Log.Format) != d.logFmt {
+		d.logger.Warn("log.format only changes when the daemon restarts",
+			"format", d.logFmt, "configured", logFormat(cfg.Log.Format))
+	}
+
+	d.mu.Lock()
+	d.st.ConfigVersion++
+	d.st.ConfigLoaded = time.Now()
+	d.st.Rules = len(cc.rules)
+	d.st.Channels = cc.router.Channels()
+	d.mu.Unlock()
+}
+
+// reload rereads the config file. A config that does not parse or has any
+// invalid entry is rejected as a whole, with every problem in the error,
+// and the daemon keeps running on the last good one.
+func (d *Daemon) reload(trigger string) error {
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		cc, errs := compileConfig(cfg)
+		if err = errors.Join(errs...); err == nil {
+			sdNotify("RELOADING=1")
+			d.apply(cfg, cc)
+			d.setConfigError(nil)
+			d.logger.Info("config reloaded", "trigger", trigger)
+			sdNotify("READY=1")
+			return nil
+		}
+	}
+
+	d.setConfigError(err)
+	d.logger.Error("config rejected, keeping the last good config", "trigger", trigger, "err", err)
+	return err
+}
+
+func (d *Daemon) setConfigError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.st.ConfigError = ""
+	if err != nil {
+		d.st.ConfigError = err.Error()
+	}
+}
+
+// watchConfig reloads the config when its file changes. It watches the
+// directory rather than the file: editors and SaveConfig replace the file
+// by renaming a new one over it, which a watch on the old file never sees.
+func (d *Daemon) watchConfig(ctx context.Context) {
+	w, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = w.Add(config.Dir())
+		if err != nil {
+			w.Close()
+		}
+	}
+	if err != nil {
+		d.logger.Error("config watcher unavailable, changes need sentinel daemon reload", "err", err)
+		return
+	}
+	defer w.Close()
+
+	path := filepath.Clean(config.ConfigPath())
+
+	// A save usually comes as several events, reload once they settle
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(e.Name) == path && (e.Has(fsnotify.Write) || e.Has(fsnotify.Create)) {
+				settle = time.After(200 * time.Millisecond)
+			}
+
+		case <-settle:
+			settle = nil
+			// Removed without a replacement yet: wait for the Create
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			// reload logs its own errors
+			d.requestReload(ctx, "file change")
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			d.logger.Warn("config watcher error", "err", err)
+		}
+	}
+}
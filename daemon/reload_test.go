@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sentinel/config"
+)
+
+// newTestDaemon returns a daemon whose state dir and runtime dir are in a
+// temp dir, running on cfg.
+func newTestDaemon(t *testing.T, cfg *config.SentinelConfig) *Daemon {
+	t.Helper()
+	isolate(t)
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	d := New(time.Second, 100, nil)
+	t.Cleanup(func() {
+		if d.logFile != nil {
+			d.logFile.Close()
+		}
+		if d.history != nil {
+			d.history.Close()
+		}
+	})
+	if err := d.reload("test"); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func ruleNames(d *Daemon) []string {
+	var out []string
+	for _, r := range d.evaluator.Rules() {
+		out = append(out, r.Name)
+	}
+	return out
+}
+
+func TestReloadRejectsBadConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Rules = []config.AlertRule{{Name: "hot", Expr: "cpu > 90"}}
+	cfg.Channels = []config.Channel{{Name: "team", Type: "discord", Settings: []byte(`{"url": "http://127.0.0.1:1/"}`)}}
+	d := newTestDaemon(t, cfg)
+
+	rules := ruleNames(d)
+	router := d.router.Load()
+	if !slices.Contains(rules, "hot") || !slices.Equal(router.Channels(), []string{"team"}) {
+		t.Fatalf("rules %v, channels %v", rules, router.Channels())
+	}
+
+	bad := *cfg
+	bad.Rules = []config.AlertRule{{Name: "hot", Expr: "cpu >"}, {Name: "new", Expr: "mem > 1"}}
+	bad.Routes = []config.Route{{Channels: []string{"pager"}}}
+	tests := []struct {
+		name  string
+		write func() error
+		want  []string
+	}{
+		{"malformed", func() error {
+			return os.WriteFile(config.ConfigPath(), []byte("{\n  \"cpu_threshold\": 80,\n}\n"), 0o644)
+		}, []string{"config.json:3:1"}},
+		{"invalid", func() error { return config.SaveConfig(&bad) }, []string{`rule "hot"`, `unknown channel "pager"`}},
+	}
+	for _, tt := range tests {
+		if err := tt.write(); err != nil {
+			t.Fatal(err)
+		}
+		err := d.reload("test")
+		if err == nil {
+			t.Fatalf("%s config accepted", tt.name)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q lacks %q", tt.name, err, want)
+			}
+		}
+		if got := ruleNames(d); !slices.Equal(got, rules) {
+			t.Errorf("%s: rules %v, want the previous %v", tt.name, got, rules)
+		}
+		if d.router.Load() != router {
+			t.Errorf("%s: router replaced", tt.name)
+		}
+		if st := d.status(); st.ConfigError == "" {
+			t.Errorf("%s: status has no config error", tt.name)
+		}
+	}
+
+	// Once fixed, the file is picked up
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.reload("test"); err != nil {
+		t.Fatal(err)
+	}
+	if st := d.status(); st.ConfigError != "" || st.ConfigVersion != 2 {
+		t.Errorf("status after the fix: error %q, version %d", st.ConfigError, st.ConfigVersion)
+	}
+}
+
+func TestWatchConfigReloadsOnce(t *testing.T) {
+	cfg := config.Default()
+	d := newTestDaemon(t, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.watchConfig(ctx)
+
+	reloads := make(chan string, 10)
+	go func() {
+		for {
+			select {
+			case req := <-d.reloadReq:
+				reloads <- req.trigger
+				req.reply <- nil
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Save until the watcher, started asynchronously, sees a change
+	ready := false
+	for range 20 {
+		config.SaveConfig(cfg)
+		select {
+		case <-reloads:
+			ready = true
+		case <-time.After(500 * time.Millisecond):
+		}
+		if ready {
+			break
+		}
+	}
+	if !ready {
+		t.Fatal("no reload on config changes")
+	}
+	time.Sleep(500 * time.Millisecond)
+	for len(reloads) > 0 {
+		<-reloads
+	}
+
+	// One save renames a new file over the config: one reload
+	cfg.CPUThreshold = 42
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if n := len(reloads); n != 1 {
+		t.Errorf("%d reloads after one save, want 1", n)
+	}
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// sdNotify sends a state change such as "READY=1" to the service manager
+// over the datagram socket named by NOTIFY_SOCKET. It does nothing when
+// the daemon was not started by systemd with Type=notify.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	// Go maps a leading '@' to the abstract socket namespace
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogTimeout returns the WatchdogSec of the unit, or 0 when the
+// watchdog is disabled or meant for another process.
+func watchdogTimeout() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// pingWatchdog tells systemd the daemon is alive, unless no snapshot has
+// been handled within timeout: a stalled sampler should get the service
+// restarted just like a stalled main loop.
+func (d *Daemon) pingWatchdog(timeout time.Duration) {
+	d.mu.Lock()
+	last := d.st.LastTick
+	if last.IsZero() {
+		last = d.st.Started
+	}
+	d.mu.Unlock()
+
+	if time.Since(last) > timeout {
+		d.logger.Warn("skipping watchdog ping, no sample handled recently", "last", last)
+		return
+	}
+	if err := sdNotify("WATCHDOG=1"); err != nil {
+		d.logger.Warn("watchdog ping failed", "err", err)
+	}
+}
+
+// notifyStatus reports the latest tick as the unit's status text, shown by
+// systemctl status.
+func (d *Daemon) notifyStatus() {
+	st := d.status()
+	text := fmt.Sprintf("STATUS=monitoring %d processes, %d active alerts", st.Procs, len(st.Active))
+	if st.Paused {
+		text += ", alerting paused"
+	}
+	if text == d.sdStatus {
+		return
+	}
+	d.sdStatus = text
+	sdNotify(text)
+}
@@ -0,0 +1,134 @@
+// Package history keeps an append-only JSONL log of the alerts raised by the
+// daemon and of their delivery results.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"sentinel/alert"
+	"sentinel/internal/rotfile"
+)
+
+// Rotation policy: the active file is rotated once it exceeds maxSize or is
+// older than maxAge, keeping at most maxBackups old files.
+const (
+	maxSize    = 10 << 20
+	maxAge     = 7 * 24 * time.Hour
+	maxBackups = 5
+)
+
+// Record kinds.
+const (
+	KindAlert    = "alert"
+	KindDelivery = "delivery"
+)
+
+// Record is one line of the log. Alert records are written when the daemon
+// raises an alert, delivery records when a channel accepted or rejected it.
+type Record struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+
+	// KindAlert; Silenced names the silence that suppressed the notification
+	Alert    *alert.Alert `json:"alert,omitempty"`
+	Channels []string     `json:"channels,omitempty"`
+	Silenced string       `json:"silenced,omitempty"`
+
+	// KindDelivery
+	IDs      []string `json:"ids,omitempty"`
+	Channel  string   `json:"channel,omitempty"`
+	OK       bool     `json:"ok,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Attempts int      `json:"attempts,omitempty"`
+	GaveUp   bool     `json:"gave_up,omitempty"`
+}
+
+// Path returns the location of the log under the sentinel state dir.
+func Path(stateDir string) string {
+	return filepath.Join(stateDir, "alerts.jsonl")
+}
+
+// Writer appends records to the log, rotating it as needed. It is safe for
+// concurrent use.
+type Writer struct {
+	f *rotfile.File
+}
+
+// OpenWriter opens the log at path for appending.
+func OpenWriter(path string) (*Writer, error) {
+	f, err := rotfile.Open(path, rotfile.Policy{MaxSize: maxSize, MaxAge: maxAge, MaxBackups: maxBackups}, recordTime)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{f: f}, nil
+}
+
+// Append writes one record.
+func (w *Writer) Append(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.f.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the log.
+func (w *Writer) Close() error {
+	return w.f.Close()
+}
+
+// recordTime reads the time of a record, to date a log reopened after a
+// restart.
+func recordTime(line []byte) (time.Time, bool) {
+	var r Record
+	if json.Unmarshal(line, &r) != nil {
+		return time.Time{}, false
+	}
+	return r.Time, true
+}
+
+// Files returns the log files oldest first: the rotated backups followed by
+// the active file. Missing files are skipped.
+func Files(path string) []string {
+	return rotfile.Files(path, maxBackups)
+}
+
+// ReadAll reads every record of the log and its backups in time order.
+// Malformed lines, e.g. a partial write after a crash, are skipped.
+func ReadAll(path string) ([]Record, error) {
+	var out []Record
+	for _, file := range Files(path) {
+		recs, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, recs...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+func readFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var r Record
+		if json.Unmarshal(sc.Bytes(), &r) == nil {
+			out = append(out, r)
+		}
+	}
+	return out, sc.Err()
+}
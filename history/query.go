@@ -0,0 +1,127 @@
+package history
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"sentinel/alert"
+)
+
+// Entry is an alert joined with the latest delivery result per channel.
+type Entry struct {
+	Alert      alert.Alert
+	Channels   []string
+	Silenced   string
+	Deliveries map[string]Record
+}
+
+// Status summarizes the deliveries: "ok" when every channel accepted the
+// alert, "failed" when one gave up, "retrying" after a failed attempt,
+// "pending" before the first attempt, "silenced" when suppressed and "-"
+// without channels.
+func (e *Entry) Status() string {
+	if e.Silenced != "" {
+		return "silenced"
+	}
+	if len(e.Channels) == 0 {
+		return "-"
+	}
+
+	status := "ok"
+	for _, ch := range e.Channels {
+		d, ok := e.Deliveries[ch]
+		switch {
+		case !ok:
+			if status == "ok" {
+				status = "pending"
+			}
+		case d.GaveUp:
+			return "failed"
+		case !d.OK:
+			status = "retrying"
+		}
+	}
+	return status
+}
+
+// Entries joins alert and delivery records, oldest first.
+func Entries(records []Record) []*Entry {
+	var out []*Entry
+	byID := make(map[string]*Entry)
+
+	for _, r := range records {
+		switch r.Kind {
+		case KindAlert:
+			if r.Alert == nil {
+				continue
+			}
+			e := &Entry{
+				Alert:      *r.Alert,
+				Channels:   r.Channels,
+				Silenced:   r.Silenced,
+				Deliveries: make(map[string]Record),
+			}
+			byID[r.Alert.ID] = e
+			out = append(out, e)
+
+		case KindDelivery:
+			for _, id := range r.IDs {
+				if e, ok := byID[id]; ok {
+					e.Deliveries[r.Channel] = r
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Filter selects alerts by time range and process. Zero fields match all.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	Pid   int
+	Comm  *regexp.Regexp // matched against comm and the command line
+	User  string
+	Rule  string
+}
+
+// Match reports whether the alert passes the filter.
+func (f *Filter) Match(a *alert.Alert) bool {
+	if !f.Since.IsZero() && a.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && a.Time.After(f.Until) {
+		return false
+	}
+	if f.Pid != 0 && a.Pid != f.Pid {
+		return false
+	}
+	if f.Comm != nil && !f.Comm.MatchString(a.Comm) && !f.Comm.MatchString(a.Cmd) {
+		return false
+	}
+	if f.User != "" && a.User != f.User {
+		return false
+	}
+	if f.Rule != "" && a.Rule != f.Rule {
+		return false
+	}
+	return true
+}
+
+// Find returns the entry whose ID equals or uniquely starts with id.
+func Find(entries []*Entry, id string) (*Entry, bool) {
+	var found *Entry
+	for _, e := range entries {
+		if e.Alert.ID == id {
+			return e, true
+		}
+		if strings.HasPrefix(e.Alert.ID, id) {
+			if found != nil {
+				return nil, false
+			}
+			found = e
+		}
+	}
+	return found, found != nil
+}
@@ -0,0 +1,164 @@
+// Package rotfile implements an append-only file that is rotated by size
+// and age, keeping numbered backups next to it: path.1 is the newest.
+package rotfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Policy says when the active file is rotated and how many old files are
+// kept.
+type Policy struct {
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
+// File is an io.Writer appending to a file that is rotated once it exceeds
+// the policy's size or age. Every Write is expected to be a whole line, so
+// rotation never splits a record. It is safe for concurrent use.
+type File struct {
+	path string
+	// lineTime reads the time of a record, to date a file opened again
+	// after a restart. Nil or failing, the file's mtime is used.
+	lineTime func(line []byte) (time.Time, bool)
+
+	mu      sync.Mutex
+	policy  Policy
+	f       *os.File
+	size    int64
+	created time.Time
+}
+
+// Open opens the file at path for appending, creating it and its
+// directory if needed.
+func Open(path string, p Policy, lineTime func(line []byte) (time.Time, bool)) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &File{path: path, policy: p, lineTime: lineTime}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.f = file
+	f.size = st.Size()
+	f.created = st.ModTime()
+	if f.size == 0 {
+		f.created = time.Now()
+	} else if first, ok := f.firstTime(); ok {
+		f.created = first
+	}
+	return nil
+}
+
+// firstTime reads the time of the first line of the active file.
+func (f *File) firstTime() (time.Time, bool) {
+	if f.lineTime == nil {
+		return time.Time{}, false
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	if !sc.Scan() {
+		return time.Time{}, false
+	}
+	return f.lineTime(sc.Bytes())
+}
+
+// SetPolicy changes the rotation policy. It applies from the next Write.
+func (f *File) SetPolicy(p Policy) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.policy = p
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return 0, &os.PathError{Op: "write", Path: f.path, Err: os.ErrClosed}
+	}
+	if f.size > 0 && (f.size+int64(len(p)) > f.policy.MaxSize || time.Since(f.created) > f.policy.MaxAge) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N to .N+1, dropping the oldest, and starts a new
+// active file.
+func (f *File) rotate() error {
+	f.f.Close()
+	f.f = nil
+
+	os.Remove(BackupPath(f.path, f.policy.MaxBackups))
+	for i := f.policy.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(BackupPath(f.path, i), BackupPath(f.path, i+1))
+	}
+	if err := os.Rename(f.path, BackupPath(f.path, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the active file. Later writes fail.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
+
+// BackupPath returns the path of the nth newest backup of path.
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Files returns the files of path oldest first: up to maxBackups rotated
+// backups followed by the active file. Missing files are skipped.
+func Files(path string, maxBackups int) []string {
+	var out []string
+	for i := maxBackups; i >= 1; i-- {
+		if _, err := os.Stat(BackupPath(path, i)); err == nil {
+			out = append(out, BackupPath(path, i))
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		out = append(out, path)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package rotfile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := Open(path, Policy{MaxSize: 10, MaxAge: time.Hour, MaxBackups: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"one 1\n", "two 2\n", "three\n", "four\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{path + ".2", path + ".1", path}
+	if got := Files(path, 2); !slices.Equal(got, want) {
+		t.Fatalf("files %v, want %v", got, want)
+	}
+	var contents []string
+	for _, file := range Files(path, 2) {
+		b, _ := os.ReadFile(file)
+		contents = append(contents, string(b))
+	}
+	// "one 1" was dropped with the third backup
+	if got := strings.Join(contents, ""); got != "two 2\nthree\nfour\n" {
+		t.Errorf("contents %q", got)
+	}
+
+	// A larger limit applies from the next write
+	f.SetPolicy(Policy{MaxSize: 100, MaxAge: time.Hour, MaxBackups: 2})
+	f.Write([]byte("five\n"))
+	if b, _ := os.ReadFile(path); string(b) != "four\nfive\n" {
+		t.Errorf("active file %q, want four and five", b)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	if err := os.WriteFile(path, []byte(old+" started\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lineTime := func(line []byte) (time.Time, bool) {
+		ts, _, _ := strings.Cut(string(line), " ")
+		t, err := time.Parse(time.RFC3339, ts)
+		return t, err == nil
+	}
+
+	// Reopened, the file is dated by its first line, not its mtime
+	f, err := Open(path, Policy{MaxSize: 1 << 20, MaxAge: time.Hour, MaxBackups: 1}, lineTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("new\n"))
+	f.Close()
+
+	if b, _ := os.ReadFile(path); string(b) != "new\n" {
+		t.Errorf("active file %q, want only the new line", b)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 // Change from const to var so it can be reassigned
 var DefaultHZ = 1000
 
@@ -7,6 +9,7 @@ const MaxRows = 100
 
 type ProcRec struct {
 	Pid   int
+	PPid  int
 	Uid   uint32
 	User  string
 	Comm  string // ← NUEVO: nombre del programa desde /proc/<pid>/stat
@@ -14,6 +17,12 @@ type ProcRec struct {
 	Prio  int64
 	Nice  int64
 
+	Threads   int64
+	StartTime uint64 // clock ticks since boot
+	Processor int    // CPU last executed on
+	MinFlt    uint64
+	MajFlt    uint64
+
 	PrevProcTime uint64
 	CurProcTime  uint64
 	CPU          float64
@@ -22,6 +31,43 @@ type ProcRec struct {
 	RSSKB   int64
 	PMem    float64
 
+	// Disk I/O from /proc/<pid>/io; IOValid is false when unreadable
+	CurIO    IOCounters
+	PrevIO   IOCounters
+	IOValid  bool
+	ReadBps  float64 // bytes/s read from storage
+	WriteBps float64 // bytes/s written to storage, minus cancelled writes
+	SyscRps  float64
+	SyscWps  float64
+
+	FDs    int    // open file descriptors, -1 when unreadable
+	Cgroup string // cgroup path, read once per process
+
 	Cmd   string // cmdline completo
 	Alive bool
 }
+
+// IOCounters are the cumulative per-process I/O counters used for rates.
+type IOCounters struct {
+	ReadBytes           uint64
+	WriteBytes          uint64
+	CancelledWriteBytes uint64
+	SyscR               uint64
+	SyscW               uint64
+}
+
+// ProcKey identifies a process across scans. PIDs are recycled by the
+// kernel, but a (pid, starttime) pair is unique for the lifetime of a boot.
+type ProcKey struct {
+	Pid       int
+	StartTime uint64
+}
+
+func (k ProcKey) String() string {
+	return fmt.Sprintf("%d@%d", k.Pid, k.StartTime)
+}
+
+// Key returns the stable identity of the record.
+func (r *ProcRec) Key() ProcKey {
+	return ProcKey{Pid: r.Pid, StartTime: r.StartTime}
+}
@@ -0,0 +1,42 @@
+package model
+
+// Ring is a fixed-capacity FIFO of samples. Once full, each Push
+// overwrites the oldest value.
+type Ring struct {
+	data  []float64
+	next  int
+	count int
+}
+
+func NewRing(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{data: make([]float64, capacity)}
+}
+
+func (r *Ring) Push(v float64) {
+	r.data[r.next] = v
+	r.next = (r.next + 1) % len(r.data)
+	if r.count < len(r.data) {
+		r.count++
+	}
+}
+
+func (r *Ring) Len() int {
+	return r.count
+}
+
+func (r *Ring) Cap() int {
+	return len(r.data)
+}
+
+// Values returns a copy of the samples, oldest first.
+func (r *Ring) Values() []float64 {
+	out := make([]float64, r.count)
+	start := (r.next - r.count + len(r.data)) % len(r.data)
+	for i := 0; i < r.count; i++ {
+		out[i] = r.data[(start+i)%len(r.data)]
+	}
+	return out
+}
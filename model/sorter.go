@@ -15,6 +15,8 @@ const (
 	SortByVSIZE
 	SortByRSS
 	SortByTIME
+	SortByREAD
+	SortByWRITE
 )
 
 type Sorter struct {
@@ -58,6 +60,10 @@ func (s *Sorter) Sort(records []ProcRec) {
 			less = a.RSSKB < b.RSSKB
 		case SortByTIME:
 			less = a.CurProcTime < b.CurProcTime
+		case SortByREAD:
+			less = a.ReadBps < b.ReadBps
+		case SortByWRITE:
+			less = a.WriteBps < b.WriteBps
 		default:
 			less = a.CPU < b.CPU
 		}
@@ -70,6 +76,6 @@ func (s *Sorter) Sort(records []ProcRec) {
 }
 
 func (s *Sorter) ColumnName() string {
-	names := []string{"CPU", "MEM", "PID", "USER", "VSIZE", "RSS", "TIME"}
+	names := []string{"CPU", "MEM", "PID", "USER", "VSIZE", "RSS", "TIME", "READ/s", "WRITE/s"}
 	return names[s.Column]
 }
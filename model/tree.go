@@ -0,0 +1,126 @@
+package model
+
+// TreeNode is a process with its children, as reported by PPid.
+// Subtree* fields aggregate the node and all of its descendants.
+type TreeNode struct {
+	Rec      ProcRec
+	Children []*TreeNode
+
+	SubtreeCPU   float64
+	SubtreePMem  float64
+	SubtreeRSSKB int64
+	SubtreeCount int
+}
+
+// TreeRow is one visible line of a flattened tree.
+type TreeRow struct {
+	Node      *TreeNode
+	Depth     int
+	Prefix    string // box-drawing guides, e.g. "│  ├─ "
+	Collapsed bool
+}
+
+// BuildTree links alive records into a forest using PPid. Records whose
+// parent is missing (pid 1, kernel threads, filtered-out parents) become roots.
+// Siblings are ordered with the given sorter.
+func BuildTree(records []ProcRec, sorter *Sorter) []*TreeNode {
+	nodes := make(map[int]*TreeNode, len(records))
+	order := make([]*TreeNode, 0, len(records))
+	for _, r := range records {
+		if !r.Alive {
+			continue
+		}
+		n := &TreeNode{Rec: r}
+		nodes[r.Pid] = n
+		order = append(order, n)
+	}
+
+	roots := make([]*TreeNode, 0)
+	for _, n := range order {
+		parent, ok := nodes[n.Rec.PPid]
+		if !ok || parent == n {
+			roots = append(roots, n)
+			continue
+		}
+		parent.Children = append(parent.Children, n)
+	}
+
+	sortNodes(roots, sorter)
+	for _, n := range roots {
+		aggregate(n, sorter)
+	}
+	return roots
+}
+
+// aggregate fills Subtree* values bottom-up and sorts each level.
+func aggregate(n *TreeNode, sorter *Sorter) {
+	n.SubtreeCPU = n.Rec.CPU
+	n.SubtreePMem = n.Rec.PMem
+	n.SubtreeRSSKB = n.Rec.RSSKB
+	n.SubtreeCount = 1
+
+	sortNodes(n.Children, sorter)
+	for _, c := range n.Children {
+		aggregate(c, sorter)
+		n.SubtreeCPU += c.SubtreeCPU
+		n.SubtreePMem += c.SubtreePMem
+		n.SubtreeRSSKB += c.SubtreeRSSKB
+		n.SubtreeCount += c.SubtreeCount
+	}
+}
+
+func sortNodes(nodes []*TreeNode, sorter *Sorter) {
+	if sorter == nil || len(nodes) < 2 {
+		return
+	}
+
+	recs := make([]ProcRec, len(nodes))
+	byPid := make(map[int]*TreeNode, len(nodes))
+	for i, n := range nodes {
+		recs[i] = n.Rec
+		byPid[n.Rec.Pid] = n
+	}
+	sorter.Sort(recs)
+	for i := range recs {
+		nodes[i] = byPid[recs[i].Pid]
+	}
+}
+
+// FlattenTree walks the forest depth-first and returns the visible rows.
+// Children of processes present in collapsed are hidden.
+func FlattenTree(roots []*TreeNode, collapsed map[ProcKey]bool) []TreeRow {
+	rows := make([]TreeRow, 0, len(roots))
+	var walk func(n *TreeNode, depth int, guide string, last bool)
+	walk = func(n *TreeNode, depth int, guide string, last bool) {
+		prefix := ""
+		childGuide := ""
+		if depth > 0 {
+			if last {
+				prefix = guide + "└─ "
+				childGuide = guide + "   "
+			} else {
+				prefix = guide + "├─ "
+				childGuide = guide + "│  "
+			}
+		}
+
+		isCollapsed := collapsed[n.Rec.Key()] && len(n.Children) > 0
+		rows = append(rows, TreeRow{
+			Node:      n,
+			Depth:     depth,
+			Prefix:    prefix,
+			Collapsed: isCollapsed,
+		})
+		if isCollapsed {
+			return
+		}
+		for i, c := range n.Children {
+			walk(c, depth+1, childGuide, i == len(n.Children)-1)
+		}
+	}
+
+	for _, r := range roots {
+		walk(r, 0, "", true)
+	}
+	return rows
+}
@@ -5,17 +5,25 @@ import (
 	"sentinel/model"
 	"sentinel/proc"
 	"sort"
+	"sync/atomic"
+	"time"
 )
 
 type Collector struct {
 	Records []model.ProcRec
-	PidMap  map[int]int
+	// PidMap indexes Records by process identity, so a recycled PID
+	// gets a fresh record instead of inheriting the old counters.
+	PidMap map[model.ProcKey]int
+
+	// countFDs enables reading /proc/<pid>/fd, a directory listing per
+	// process and scan; FDs is -1 while it is off
+	countFDs atomic.Bool
 }
 
 func NewCollector() *Collector {
 	return &Collector{
 		Records: make([]model.ProcRec, 0, model.MaxRows),
-		PidMap:  make(map[int]int),
+		PidMap:  make(map[model.ProcKey]int),
 	}
 }
 
@@ -28,7 +36,8 @@ func (c *Collector) Scan() (int, int) {
 	totalTasks := 0
 	runningTasks := 0
 
-	seen := make(map[int]bool)
+	seen := make(map[model.ProcKey]bool)
+	countFDs := c.countFDs.Load()
 
 	for _, e := range entries {
 		name := e.Name()
@@ -43,62 +52,100 @@ func (c *Collector) Scan() (int, int) {
 
 		totalTasks++
 
-		comm, state, utime, stime, prio, nice, _, _, vsizeKB, rssKB, ok := proc.ReadProcStat(pid)
+		st, ok := proc.ReadProcStat(pid)
 		if !ok {
 			continue
 		}
 
-		if state == 'R' {
+		if st.State == 'R' {
 			runningTasks++
 		}
 
-		seen[pid] = true
+		key := model.ProcKey{Pid: pid, StartTime: st.StartTime}
+		seen[key] = true
 
 		uid := proc.ReadStatusUID(pid)
 		user := proc.UIDToName(uid)
 		cmd := proc.ReadCmdline(pid)
 
-		curProcTime := utime + stime
+		fds := -1
+		if countFDs {
+			fds = proc.CountFDs(pid)
+		}
 
-		idx, exists := c.PidMap[pid]
+		curProcTime := st.CPUTime()
+		pio, ioOK := proc.ReadProcIO(pid)
+		curIO := model.IOCounters{
+			ReadBytes:           pio.ReadBytes,
+			WriteBytes:          pio.WriteBytes,
+			CancelledWriteBytes: pio.CancelledWriteBytes,
+			SyscR:               pio.SyscR,
+			SyscW:               pio.SyscW,
+		}
+
+		idx, exists := c.PidMap[key]
 		if exists {
 			rec := &c.Records[idx]
 			rec.Alive = true
 			rec.User = user
-			rec.Comm = comm // ← ACTUALIZAR
-			rec.State = state
-			rec.Prio = prio
-			rec.Nice = nice
+			rec.Comm = st.Comm // ← ACTUALIZAR
+			rec.PPid = st.PPid
+			rec.State = st.State
+			rec.Prio = st.Priority
+			rec.Nice = st.Nice
+			rec.Threads = st.NumThreads
+			rec.StartTime = st.StartTime
+			rec.Processor = st.Processor
+			rec.MinFlt = st.MinFlt
+			rec.MajFlt = st.MajFlt
 			rec.CurProcTime = curProcTime
-			rec.VSizeKB = vsizeKB
-			rec.RSSKB = rssKB
+			rec.VSizeKB = st.VSizeKB
+			rec.RSSKB = st.RSSKB
+			if !rec.IOValid {
+				// First readable sample: start rates from zero
+				rec.PrevIO = curIO
+			}
+			rec.CurIO = curIO
+			rec.IOValid = ioOK
+			rec.FDs = fds
 			rec.Cmd = cmd
 		} else {
 			newRec := model.ProcRec{
 				Pid:          pid,
+				PPid:         st.PPid,
 				Uid:          uid,
 				User:         user,
-				Comm:         comm, // ← GUARDAR COMM
-				State:        state,
-				Prio:         prio,
-				Nice:         nice,
+				Comm:         st.Comm, // ← GUARDAR COMM
+				State:        st.State,
+				Prio:         st.Priority,
+				Nice:         st.Nice,
+				Threads:      st.NumThreads,
+				StartTime:    st.StartTime,
+				Processor:    st.Processor,
+				MinFlt:       st.MinFlt,
+				MajFlt:       st.MajFlt,
 				PrevProcTime: 0,
 				CurProcTime:  curProcTime,
 				CPU:          0,
-				VSizeKB:      vsizeKB,
-				RSSKB:        rssKB,
+				VSizeKB:      st.VSizeKB,
+				RSSKB:        st.RSSKB,
+				CurIO:        curIO,
+				PrevIO:       curIO,
+				IOValid:      ioOK,
+				FDs:          fds,
+				Cgroup:       proc.ReadCgroup(pid),
 				PMem:         0,
 				Cmd:          cmd,
 				Alive:        true,
 			}
 			c.Records = append(c.Records, newRec)
-			c.PidMap[pid] = len(c.Records) - 1
+			c.PidMap[key] = len(c.Records) - 1
 		}
 	}
 
 	for i := range c.Records {
 		rec := &c.Records[i]
-		if !seen[rec.Pid] {
+		if !seen[rec.Key()] {
 			rec.Alive = false
 		}
 	}
@@ -115,9 +162,9 @@ func (c *Collector) Compact() {
 	}
 	c.Records = alive
 
-	c.PidMap = make(map[int]int)
+	c.PidMap = make(map[model.ProcKey]int)
 	for i := range c.Records {
-		c.PidMap[c.Records[i].Pid] = i
+		c.PidMap[c.Records[i].Key()] = i
 	}
 }
 
@@ -126,3 +173,41 @@ func SortByCPU(records []model.ProcRec) {
 		return records[i].CPU > records[j].CPU
 	})
 }
+
+// computeIORates updates per-second disk I/O rates for alive records from the
+// counter deltas since the previous call, then rolls Cur into Prev.
+func computeIORates(records []model.ProcRec, elapsed time.Duration) {
+	secs := elapsed.Seconds()
+	for i := range records {
+		r := &records[i]
+		if !r.Alive {
+			continue
+		}
+		if !r.IOValid || secs <= 0 {
+			r.ReadBps, r.WriteBps, r.SyscRps, r.SyscWps = 0, 0, 0, 0
+			r.PrevIO = r.CurIO
+			continue
+		}
+
+		cur, prev := r.CurIO, r.PrevIO
+		written := counterDelta(cur.WriteBytes, prev.WriteBytes)
+		cancelled := counterDelta(cur.CancelledWriteBytes, prev.CancelledWriteBytes)
+		if cancelled > written {
+			cancelled = written
+		}
+
+		r.ReadBps = float64(counterDelta(cur.ReadBytes, prev.ReadBytes)) / secs
+		r.WriteBps = float64(written-cancelled) / secs
+		r.SyscRps = float64(counterDelta(cur.SyscR, prev.SyscR)) / secs
+		r.SyscWps = float64(counterDelta(cur.SyscW, prev.SyscW)) / secs
+
+		r.PrevIO = r.CurIO
+	}
+}
+
+func counterDelta(cur, prev uint64) uint64 {
+	if cur > prev {
+		return cur - prev
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"sync"
+
+	"sentinel/model"
+)
+
+// HistoryLen is the number of samples kept per series
+// (3 minutes at the TUI's 1.5s interval).
+const HistoryLen = 120
+
+// Series is a copy of a process or system history, oldest sample first.
+type Series struct {
+	CPU      []float64 // %CPU (system: busy %)
+	RSSKB    []float64 // RSS in KB (system: used memory in KB)
+	ReadBps  []float64
+	WriteBps []float64
+}
+
+type seriesRings struct {
+	cpu, rss, read, write *model.Ring
+}
+
+func newSeriesRings(size int) *seriesRings {
+	return &seriesRings{
+		cpu:   model.NewRing(size),
+		rss:   model.NewRing(size),
+		read:  model.NewRing(size),
+		write: model.NewRing(size),
+	}
+}
+
+func (s *seriesRings) push(cpu, rss, read, write float64) {
+	s.cpu.Push(cpu)
+	s.rss.Push(rss)
+	s.read.Push(read)
+	s.write.Push(write)
+}
+
+func (s *seriesRings) series() Series {
+	return Series{
+		CPU:      s.cpu.Values(),
+		RSSKB:    s.rss.Values(),
+		ReadBps:  s.read.Values(),
+		WriteBps: s.write.Values(),
+	}
+}
+
+// History keeps bounded per-process and system metric series. The sampler
+// writes to it once per cycle; readers get copies and may call it from any
+// goroutine.
+type History struct {
+	mu     sync.RWMutex
+	size   int
+	procs  map[model.ProcKey]*seriesRings
+	system *seriesRings
+}
+
+func NewHistory(size int) *History {
+	return &History{
+		size:   size,
+		procs:  make(map[model.ProcKey]*seriesRings),
+		system: newSeriesRings(size),
+	}
+}
+
+// Process returns the history of the given process, ok=false if unknown.
+func (h *History) Process(key model.ProcKey) (Series, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s, ok := h.procs[key]
+	if !ok {
+		return Series{}, false
+	}
+	return s.series(), true
+}
+
+// System returns the host CPU busy %, used memory and summed disk I/O history.
+func (h *History) System() Series {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.system.series()
+}
+
+// record appends one sample per alive record and for the system, and drops
+// series of processes that are gone.
+func (h *History) record(snap *Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	seen := make(map[model.ProcKey]bool, len(snap.Records))
+	var read, write float64
+	for i := range snap.Records {
+		r := &snap.Records[i]
+		key := r.Key()
+		seen[key] = true
+
+		s, ok := h.procs[key]
+		if !ok {
+			s = newSeriesRings(h.size)
+			h.procs[key] = s
+		}
+		s.push(r.CPU, float64(r.RSSKB), r.ReadBps, r.WriteBps)
+		read += r.ReadBps
+		write += r.WriteBps
+	}
+
+	for key := range h.procs {
+		if !seen[key] {
+			delete(h.procs, key)
+		}
+	}
+
+	h.system.push(snap.CPU.Busy, float64(snap.Mem.UsedKB()), read, write)
+}
@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sentinel/model"
+	"sentinel/proc"
+)
+
+// Snapshot is the result of one collection cycle. It is shared by every
+// subscriber and must be treated as read-only.
+type Snapshot struct {
+	Time         time.Time
+	Seq          uint64
+	ScanDuration time.Duration
+
+	Records []model.ProcRec // alive processes only
+	Tasks   int
+	Running int
+
+	Loads  [3]float64
+	Uptime float64
+	CPU    proc.CPUUsage
+	Cores  []proc.CPUUsage
+	Mem    proc.MemInfo
+
+	// History is shared and keeps growing after this snapshot was taken
+	History *History
+}
+
+// Sampler owns the collection loop. It scans /proc once per interval,
+// computes CPU/MEM/IO metrics and publishes a Snapshot to all subscribers,
+// so the TUI, the daemon and exporters share one implementation.
+type Sampler struct {
+	Collector *Collector
+	History   *History
+	interval  time.Duration
+
+	mu     sync.Mutex
+	subs   map[int]chan *Snapshot
+	nextID int
+	last   *Snapshot
+
+	seq       uint64
+	prevTotal int64
+	memTotal  int64
+	prevCPU   proc.CPUStat
+	lastTick  time.Time
+}
+
+func NewSampler(interval time.Duration) *Sampler {
+	return &Sampler{
+		Collector: NewCollector(),
+		History:   NewHistory(HistoryLen),
+		interval:  interval,
+		subs:      make(map[int]chan *Snapshot),
+	}
+}
+
+// SetCountFDs turns counting the open descriptors of every process on or
+// off. It is off by default since it lists a directory per process; the
+// records then have FDs -1. Safe to call while Run is running.
+func (s *Sampler) SetCountFDs(on bool) {
+	s.Collector.countFDs.Store(on)
+}
+
+// Interval returns the time between two collection cycles.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Subscribe registers a consumer and returns its channel plus a function
+// that unregisters it. Slow consumers only ever see the latest snapshot:
+// when the buffer is full the oldest pending snapshot is dropped.
+// The channel is closed when Run returns.
+func (s *Sampler) Subscribe() (<-chan *Snapshot, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	ch := make(chan *Snapshot, 1)
+	s.subs[id] = ch
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if c, ok := s.subs[id]; ok {
+			delete(s.subs, id)
+			close(c)
+		}
+	}
+	return ch, cancel
+}
+
+// Latest returns the most recent snapshot, or nil before the first cycle.
+func (s *Sampler) Latest() *Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Run collects until ctx is cancelled, then closes all subscriber channels.
+func (s *Sampler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer s.closeAll()
+
+	s.prevTotal = int64(proc.ReadTotalCPUTime())
+	s.memTotal = proc.ReadMemTotalKB()
+	s.prevCPU, _ = proc.ReadCPUStat()
+	s.lastTick = time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+			s.publish(s.Sample())
+		}
+	}
+}
+
+// Sample performs one collection cycle: scans processes, updates metrics,
+// compacts records and reads system load/uptime/CPU/memory usage.
+// Run calls it once per tick; it is not safe for concurrent use.
+func (s *Sampler) Sample() *Snapshot {
+	start := time.Now()
+	tasks, running := s.Collector.Scan()
+
+	curTotal := int64(proc.ReadTotalCPUTime())
+	sysDelta := int64(1)
+	if curTotal > s.prevTotal {
+		sysDelta = curTotal - s.prevTotal
+	}
+
+	computeMetrics(s.Collector.Records, sysDelta, s.memTotal)
+
+	now := time.Now()
+	computeIORates(s.Collector.Records, now.Sub(s.lastTick))
+	s.lastTick = now
+
+	s.prevTotal = curTotal
+	s.memTotal = proc.ReadMemTotalKB()
+
+	s.Collector.Compact()
+
+	cpu, cores := s.cpuUsage()
+	mem, _ := proc.ReadMemInfo()
+
+	records := make([]model.ProcRec, len(s.Collector.Records))
+	copy(records, s.Collector.Records)
+
+	s.seq++
+	snap := &Snapshot{
+		Time:         now,
+		Seq:          s.seq,
+		ScanDuration: time.Since(start),
+		Records:      records,
+		Tasks:        tasks,
+		Running:      running,
+		Loads:        proc.ReadLoadavg(),
+		Uptime:       proc.ReadUptime(),
+		CPU:          cpu,
+		Cores:        cores,
+		Mem:          mem,
+		History:      s.History,
+	}
+	s.History.record(snap)
+	return snap
+}
+
+func (s *Sampler) publish(snap *Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last = snap
+	for _, ch := range s.subs {
+		select {
+		case ch <- snap:
+		default:
+			// Drop the stale snapshot and deliver the fresh one
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- snap
+		}
+	}
+}
+
+func (s *Sampler) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, ch := range s.subs {
+		delete(s.subs, id)
+		close(ch)
+	}
+}
+
+// cpuUsage samples /proc/stat and returns the aggregate and per-core usage
+// since the previous tick.
+func (s *Sampler) cpuUsage() (proc.CPUUsage, []proc.CPUUsage) {
+	cur, ok := proc.ReadCPUStat()
+	if !ok {
+		return proc.CPUUsage{}, nil
+	}
+
+	all := proc.CPUDelta(s.prevCPU.All, cur.All)
+	cores := proc.CoreDeltas(s.prevCPU, cur)
+	s.prevCPU = cur
+	return all, cores
+}
+
+// computeMetrics updates %CPU and %MEM for alive records using deltas.
+func computeMetrics(records []model.ProcRec, sysDelta int64, memTotal int64) {
+	for i := range records {
+		r := &records[i]
+		if !r.Alive {
+			continue
+		}
+
+		if r.PrevProcTime == 0 {
+			r.CPU = 0
+		} else {
+			procDelta := uint64(0)
+			if r.CurProcTime > r.PrevProcTime {
+				procDelta = r.CurProcTime - r.PrevProcTime
+			}
+			r.CPU = float64(procDelta) * 100.0 / float64(sysDelta)
+		}
+
+		if memTotal > 0 {
+			r.PMem = float64(r.RSSKB) * 100.0 / float64(memTotal)
+		}
+
+		r.PrevProcTime = r.CurProcTime
+	}
+}
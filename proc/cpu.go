@@ -3,8 +3,8 @@ package proc
 import (
 	"bufio"
 	"os"
-	"strings"
 	"strconv"
+	"strings"
 )
 
 func ReadTotalCPUTime() uint64 {
@@ -33,4 +33,161 @@ func ReadTotalCPUTime() uint64 {
 		}
 	}
 	return total
-}
\ No newline at end of file
+}
+
+// CPUTimes holds the jiffy counters of a single "cpu" line in /proc/stat.
+type CPUTimes struct {
+	User      uint64
+	Nice      uint64
+	System    uint64
+	Idle      uint64
+	IOWait    uint64
+	IRQ       uint64
+	SoftIRQ   uint64
+	Steal     uint64
+	Guest     uint64
+	GuestNice uint64
+}
+
+// Total returns all jiffies spent by the CPU. Guest time is already
+// accounted in user/nice by the kernel, so it is not added twice.
+func (t CPUTimes) Total() uint64 {
+	return t.User + t.Nice + t.System + t.Idle + t.IOWait +
+		t.IRQ + t.SoftIRQ + t.Steal
+}
+
+// IdleTotal returns jiffies where the CPU did no work (idle + iowait).
+func (t CPUTimes) IdleTotal() uint64 {
+	return t.Idle + t.IOWait
+}
+
+// CPUStat is a snapshot of the aggregate and per-core lines of /proc/stat.
+type CPUStat struct {
+	All   CPUTimes
+	Cores []CPUTimes
+}
+
+// ReadCPUStat parses the aggregate "cpu" line and every "cpuN" line of /proc/stat.
+// Returns ok=false if the file cannot be read or has no aggregate line.
+func ReadCPUStat() (stat CPUStat, ok bool) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "cpu") {
+			// cpu lines are always first, stop at intr/ctxt/...
+			break
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		times := parseCPUTimes(fields[1:])
+		if fields[0] == "cpu" {
+			stat.All = times
+			ok = true
+			continue
+		}
+
+		idx, err := strconv.Atoi(fields[0][3:])
+		if err != nil || idx < 0 {
+			continue
+		}
+		// Offline cores are omitted, keep indexes aligned with core numbers
+		for len(stat.Cores) <= idx {
+			stat.Cores = append(stat.Cores, CPUTimes{})
+		}
+		stat.Cores[idx] = times
+	}
+	return
+}
+
+func parseCPUTimes(fields []string) CPUTimes {
+	var v [10]uint64
+	for i := 0; i < len(fields) && i < len(v); i++ {
+		v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+	}
+	return CPUTimes{
+		User:      v[0],
+		Nice:      v[1],
+		System:    v[2],
+		Idle:      v[3],
+		IOWait:    v[4],
+		IRQ:       v[5],
+		SoftIRQ:   v[6],
+		Steal:     v[7],
+		Guest:     v[8],
+		GuestNice: v[9],
+	}
+}
+
+// CPUUsage is the percentage breakdown of CPU time between two samples.
+// Busy is everything except idle and iowait.
+type CPUUsage struct {
+	User    float64
+	Nice    float64
+	System  float64
+	Idle    float64
+	IOWait  float64
+	IRQ     float64
+	SoftIRQ float64
+	Steal   float64
+	Guest   float64
+	Busy    float64
+}
+
+// CPUDelta computes the usage breakdown between prev and cur samples.
+// Counters that went backwards (e.g. core hotplug) are treated as zero.
+func CPUDelta(prev, cur CPUTimes) CPUUsage {
+	total := sub(cur.Total(), prev.Total())
+	if total == 0 {
+		return CPUUsage{}
+	}
+
+	pct := func(a, b uint64) float64 {
+		return float64(sub(a, b)) * 100.0 / float64(total)
+	}
+
+	u := CPUUsage{
+		User:    pct(cur.User, prev.User),
+		Nice:    pct(cur.Nice, prev.Nice),
+		System:  pct(cur.System, prev.System),
+		Idle:    pct(cur.Idle, prev.Idle),
+		IOWait:  pct(cur.IOWait, prev.IOWait),
+		IRQ:     pct(cur.IRQ, prev.IRQ),
+		SoftIRQ: pct(cur.SoftIRQ, prev.SoftIRQ),
+		Steal:   pct(cur.Steal, prev.Steal),
+		Guest:   pct(cur.Guest+cur.GuestNice, prev.Guest+prev.GuestNice),
+	}
+	u.Busy = 100.0 - pct(cur.IdleTotal(), prev.IdleTotal())
+	if u.Busy < 0 {
+		u.Busy = 0
+	}
+	return u
+}
+
+// CoreDeltas computes per-core usage between two snapshots.
+// Cores missing from prev are reported with zero usage.
+func CoreDeltas(prev, cur CPUStat) []CPUUsage {
+	usages := make([]CPUUsage, len(cur.Cores))
+	for i := range cur.Cores {
+		if i < len(prev.Cores) {
+			usages[i] = CPUDelta(prev.Cores[i], cur.Cores[i])
+		}
+	}
+	return usages
+}
+
+func sub(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return 0
+}
@@ -0,0 +1,235 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatusField is one "Key: value" line of /proc/<pid>/status.
+type StatusField struct {
+	Key   string
+	Value string
+}
+
+// ReadStatus returns all fields of /proc/<pid>/status in file order.
+func ReadStatus(pid int) ([]StatusField, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fields []StatusField
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, val, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields = append(fields, StatusField{Key: key, Value: strings.TrimSpace(val)})
+	}
+	return fields, scanner.Err()
+}
+
+// Limit is one resource limit from /proc/<pid>/limits.
+type Limit struct {
+	Name  string
+	Soft  string
+	Hard  string
+	Units string
+}
+
+// limitNameWidth is the fixed width of the "Limit" column in /proc/<pid>/limits.
+const limitNameWidth = 26
+
+// ReadLimits parses /proc/<pid>/limits.
+func ReadLimits(pid int) ([]Limit, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var limits []Limit
+	scanner := bufio.NewScanner(f)
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			// Header line
+			first = false
+			continue
+		}
+		if len(line) <= limitNameWidth {
+			continue
+		}
+
+		rest := strings.Fields(line[limitNameWidth:])
+		if len(rest) < 2 {
+			continue
+		}
+		l := Limit{
+			Name: strings.TrimSpace(line[:limitNameWidth]),
+			Soft: rest[0],
+			Hard: rest[1],
+		}
+		if len(rest) > 2 {
+			l.Units = rest[2]
+		}
+		limits = append(limits, l)
+	}
+	return limits, scanner.Err()
+}
+
+// FDInfo is an open file descriptor and what it points to.
+type FDInfo struct {
+	FD     int
+	Target string
+}
+
+// ReadFDs lists /proc/<pid>/fd sorted by descriptor number.
+// Reading another user's fds requires root.
+func ReadFDs(pid int) ([]FDInfo, error) {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fds := make([]FDInfo, 0, len(entries))
+	for _, e := range entries {
+		fd, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(dir + "/" + e.Name())
+		if err != nil {
+			// fd closed while listing
+			continue
+		}
+		fds = append(fds, FDInfo{FD: fd, Target: target})
+	}
+
+	sort.Slice(fds, func(i, j int) bool { return fds[i].FD < fds[j].FD })
+	return fds, nil
+}
+
+// CountFDs returns the number of open descriptors, or -1 if unreadable.
+func CountFDs(pid int) int {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// MapEntry is one mapping of /proc/<pid>/maps.
+type MapEntry struct {
+	Start  uint64
+	End    uint64
+	Perms  string
+	Offset uint64
+	Dev    string
+	Inode  uint64
+	Path   string // empty for anonymous mappings
+}
+
+// SizeKB returns the size of the mapping in KB.
+func (m MapEntry) SizeKB() int64 {
+	return int64(m.End-m.Start) / 1024
+}
+
+// ReadMaps parses /proc/<pid>/maps.
+func ReadMaps(pid int) ([]MapEntry, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var maps []MapEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		startS, endS, found := strings.Cut(fields[0], "-")
+		if !found {
+			continue
+		}
+
+		var m MapEntry
+		m.Start, _ = strconv.ParseUint(startS, 16, 64)
+		m.End, _ = strconv.ParseUint(endS, 16, 64)
+		m.Perms = fields[1]
+		m.Offset, _ = strconv.ParseUint(fields[2], 16, 64)
+		m.Dev = fields[3]
+		m.Inode, _ = strconv.ParseUint(fields[4], 10, 64)
+		if len(fields) > 5 {
+			m.Path = strings.Join(fields[5:], " ")
+		}
+		maps = append(maps, m)
+	}
+	return maps, scanner.Err()
+}
+
+// ReadEnviron returns the initial environment of the process as KEY=VALUE strings.
+func ReadEnviron(pid int) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, kv := range strings.Split(string(data), "\x00") {
+		if kv != "" {
+			env = append(env, kv)
+		}
+	}
+	return env, nil
+}
+
+// ReadCwd returns the current working directory of the process.
+func ReadCwd(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+}
+
+// ReadExe returns the path of the executable of the process.
+func ReadExe(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+}
+
+// ReadCgroup returns the cgroup path of the process. The unified (v2)
+// hierarchy is preferred; on hybrid/v1 hosts the systemd, memory or cpu
+// controller path is used. Returns "" if unreadable.
+func ReadCgroup(pid int) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+
+	paths := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hierarchy-ID:controller-list:path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, ctrl := range strings.Split(parts[1], ",") {
+			paths[ctrl] = parts[2]
+		}
+	}
+
+	for _, ctrl := range []string{"", "name=systemd", "memory", "cpu"} {
+		if p, ok := paths[ctrl]; ok && p != "/" {
+			return p
+		}
+	}
+	return "/"
+}
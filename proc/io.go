@@ -0,0 +1,61 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProcIO holds the cumulative counters of /proc/<pid>/io.
+// ReadBytes/WriteBytes are what actually hit the block layer,
+// RChar/WChar include page cache hits and pipes.
+type ProcIO struct {
+	RChar               uint64
+	WChar               uint64
+	SyscR               uint64
+	SyscW               uint64
+	ReadBytes           uint64
+	WriteBytes          uint64
+	CancelledWriteBytes uint64
+}
+
+// ReadProcIO parses /proc/<pid>/io.
+// Returns ok=false if the file is unreadable, which is the case for
+// other users' processes unless running as root.
+func ReadProcIO(pid int) (pio ProcIO, ok bool) {
+	path := fmt.Sprintf("/proc/%d/io", pid)
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	targets := map[string]*uint64{
+		"rchar":                 &pio.RChar,
+		"wchar":                 &pio.WChar,
+		"syscr":                 &pio.SyscR,
+		"syscw":                 &pio.SyscW,
+		"read_bytes":            &pio.ReadBytes,
+		"write_bytes":           &pio.WriteBytes,
+		"cancelled_write_bytes": &pio.CancelledWriteBytes,
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, val, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		dst, wanted := targets[key]
+		if !wanted {
+			continue
+		}
+		if v, err := strconv.ParseUint(strings.TrimSpace(val), 10, 64); err == nil {
+			*dst = v
+			ok = true
+		}
+	}
+	return
+}
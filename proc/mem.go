@@ -1,10 +1,10 @@
 package proc
 
 import (
-	"os"
 	"bufio"
-	"strings"
+	"os"
 	"strconv"
+	"strings"
 )
 
 func ReadMemTotalKB() int64 {
@@ -27,4 +27,131 @@ func ReadMemTotalKB() int64 {
 		}
 	}
 	return 1
-}
\ No newline at end of file
+}
+
+// MemInfo is a snapshot of /proc/meminfo. Sizes are in KB,
+// HugePages counters are in pages of HugePageSizeKB.
+type MemInfo struct {
+	MemTotalKB     int64
+	MemFreeKB      int64
+	MemAvailableKB int64
+	BuffersKB      int64
+	CachedKB       int64
+	SReclaimableKB int64
+	ShmemKB        int64
+	SwapTotalKB    int64
+	SwapFreeKB     int64
+	SwapCachedKB   int64
+	DirtyKB        int64
+	WritebackKB    int64
+
+	HugePagesTotal int64
+	HugePagesFree  int64
+	HugePageSizeKB int64
+}
+
+// ReadMemInfo parses /proc/meminfo.
+// Returns ok=false if the file cannot be read or MemTotal is missing.
+func ReadMemInfo() (info MemInfo, ok bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	targets := map[string]*int64{
+		"MemTotal":        &info.MemTotalKB,
+		"MemFree":         &info.MemFreeKB,
+		"MemAvailable":    &info.MemAvailableKB,
+		"Buffers":         &info.BuffersKB,
+		"Cached":          &info.CachedKB,
+		"SReclaimable":    &info.SReclaimableKB,
+		"Shmem":           &info.ShmemKB,
+		"SwapTotal":       &info.SwapTotalKB,
+		"SwapFree":        &info.SwapFreeKB,
+		"SwapCached":      &info.SwapCachedKB,
+		"Dirty":           &info.DirtyKB,
+		"Writeback":       &info.WritebackKB,
+		"HugePages_Total": &info.HugePagesTotal,
+		"HugePages_Free":  &info.HugePagesFree,
+		"Hugepagesize":    &info.HugePageSizeKB,
+	}
+
+	hasAvailable := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		dst, wanted := targets[key]
+		if !wanted {
+			continue
+		}
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		*dst = v
+		if key == "MemAvailable" {
+			hasAvailable = true
+		}
+	}
+
+	if info.MemTotalKB <= 0 {
+		return
+	}
+
+	// Kernels older than 3.14 lack MemAvailable, approximate it
+	if !hasAvailable {
+		info.MemAvailableKB = info.MemFreeKB + info.BuffersKB + info.CachedKB + info.SReclaimableKB - info.ShmemKB
+	}
+
+	ok = true
+	return
+}
+
+// UsedKB returns memory that cannot be reclaimed (total - available).
+func (m MemInfo) UsedKB() int64 {
+	used := m.MemTotalKB - m.MemAvailableKB
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// CacheKB returns page cache plus reclaimable slab, excluding shared memory.
+func (m MemInfo) CacheKB() int64 {
+	c := m.CachedKB + m.SReclaimableKB - m.ShmemKB
+	if c < 0 {
+		return 0
+	}
+	return c
+}
+
+// UsedPercent returns UsedKB as a percentage of MemTotal.
+func (m MemInfo) UsedPercent() float64 {
+	if m.MemTotalKB <= 0 {
+		return 0
+	}
+	return float64(m.UsedKB()) * 100.0 / float64(m.MemTotalKB)
+}
+
+// SwapUsedKB returns swap in use, excluding pages also present in RAM.
+func (m MemInfo) SwapUsedKB() int64 {
+	used := m.SwapTotalKB - m.SwapFreeKB - m.SwapCachedKB
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// SwapUsedPercent returns SwapUsedKB as a percentage of SwapTotal.
+// Hosts without swap report 0.
+func (m MemInfo) SwapUsedPercent() float64 {
+	if m.SwapTotalKB <= 0 {
+		return 0
+	}
+	return float64(m.SwapUsedKB()) * 100.0 / float64(m.SwapTotalKB)
+}
@@ -3,7 +3,6 @@ package proc
 import (
     "bufio"
     "fmt"
-    "io"
     "os"
     "os/user"
     "strconv"
@@ -61,58 +60,173 @@ func ReadCmdline(pid int) string {
     return strings.TrimSpace(string(data))
 }
 
-// ReadProcStat parses /proc/<pid>/stat and extracts process metrics.
-// Returns ok=false if parsing fails or process doesn't exist.
-func ReadProcStat(pid int) (comm string, state byte, utime, stime uint64,
-    prio, nicev, nthreads int64, starttime uint64, vsizeKB, rssKB int64, ok bool) {
+// ProcStat holds every field of /proc/<pid>/stat (see proc(5)).
+// Time values are in clock ticks, VSizeKB and RSSKB are converted to KB.
+type ProcStat struct {
+    Pid                 int
+    Comm                string
+    State               byte
+    PPid                int
+    Pgrp                int
+    Session             int
+    TTYNr               int
+    TPGid               int
+    Flags               uint64
+    MinFlt              uint64
+    CMinFlt             uint64
+    MajFlt              uint64
+    CMajFlt             uint64
+    UTime               uint64
+    STime               uint64
+    CUTime              int64
+    CSTime              int64
+    Priority            int64
+    Nice                int64
+    NumThreads          int64
+    ItRealValue         int64
+    StartTime           uint64
+    VSizeKB             int64
+    RSSKB               int64
+    RSSLim              uint64
+    StartCode           uint64
+    EndCode             uint64
+    StartStack          uint64
+    KStkESP             uint64
+    KStkEIP             uint64
+    Signal              uint64
+    Blocked             uint64
+    SigIgnore           uint64
+    SigCatch            uint64
+    WChan               uint64
+    NSwap               uint64
+    CNSwap              uint64
+    ExitSignal          int
+    Processor           int
+    RTPriority          uint64
+    Policy              uint64
+    DelayAcctBlkIOTicks uint64
+    GuestTime           uint64
+    CGuestTime          int64
+    StartData           uint64
+    EndData             uint64
+    StartBrk            uint64
+    ArgStart            uint64
+    ArgEnd              uint64
+    EnvStart            uint64
+    EnvEnd              uint64
+    ExitCode            int
+}
 
-    path := fmt.Sprintf("/proc/%d/stat", pid)
-    f, err := os.Open(path)
-    if err != nil {
-        return
-    }
-    defer f.Close()
+// CPUTime returns user plus system time in clock ticks.
+func (s ProcStat) CPUTime() uint64 {
+    return s.UTime + s.STime
+}
 
-    data, err := io.ReadAll(f)
+// ReadProcStat parses /proc/<pid>/stat into a ProcStat.
+// Fields missing on older kernels are left as zero.
+// Returns ok=false if parsing fails or process doesn't exist.
+func ReadProcStat(pid int) (st ProcStat, ok bool) {
+    path := fmt.Sprintf("/proc/%d/stat", pid)
+    data, err := os.ReadFile(path)
     if err != nil {
         return
     }
+    return ParseProcStat(string(data))
+}
 
-    line := strings.TrimSpace(string(data))
+// ParseProcStat parses the contents of a /proc/<pid>/stat file.
+func ParseProcStat(data string) (st ProcStat, ok bool) {
+    line := strings.TrimSpace(data)
 
-    // Find command name between parentheses
+    // Find command name between parentheses; comm may itself contain ')'
     l := strings.IndexByte(line, '(')
     r := strings.LastIndexByte(line, ')')
     if l < 0 || r < 0 || r <= l {
         return
     }
 
-    comm = line[l+1 : r]
-    rest := strings.TrimSpace(line[r+1:])
-    fields := strings.Fields(rest)
-
-    if len(fields) < 24 {
+    pid, err := strconv.Atoi(strings.TrimSpace(line[:l]))
+    if err != nil {
         return
     }
 
-    // Helper to access fields (adjusting for removed comm field)
-    field := func(i int) string { return fields[i-3] }
+    st.Pid = pid
+    st.Comm = line[l+1 : r]
+    fields := strings.Fields(line[r+1:])
+
+    if len(fields) < 22 {
+        return
+    }
 
-    state = field(3)[0]
-    utime, _ = strconv.ParseUint(field(14), 10, 64)
-    stime, _ = strconv.ParseUint(field(15), 10, 64)
-    prio, _ = strconv.ParseInt(field(18), 10, 64)
-    nicev, _ = strconv.ParseInt(field(19), 10, 64)
-    nthreads, _ = strconv.ParseInt(field(20), 10, 64)
-    starttime, _ = strconv.ParseUint(field(22), 10, 64)
+    // Helpers to access fields by their proc(5) number (adjusting for pid and comm)
+    field := func(i int) string {
+        if i-3 < len(fields) {
+            return fields[i-3]
+        }
+        return ""
+    }
+    u := func(i int) uint64 {
+        v, _ := strconv.ParseUint(field(i), 10, 64)
+        return v
+    }
+    n := func(i int) int64 {
+        v, _ := strconv.ParseInt(field(i), 10, 64)
+        return v
+    }
 
-    vsize, _ := strconv.ParseInt(field(23), 10, 64)
-    rss, _ := strconv.ParseInt(field(24), 10, 64)
+    st.State = field(3)[0]
+    st.PPid = int(n(4))
+    st.Pgrp = int(n(5))
+    st.Session = int(n(6))
+    st.TTYNr = int(n(7))
+    st.TPGid = int(n(8))
+    st.Flags = u(9)
+    st.MinFlt = u(10)
+    st.CMinFlt = u(11)
+    st.MajFlt = u(12)
+    st.CMajFlt = u(13)
+    st.UTime = u(14)
+    st.STime = u(15)
+    st.CUTime = n(16)
+    st.CSTime = n(17)
+    st.Priority = n(18)
+    st.Nice = n(19)
+    st.NumThreads = n(20)
+    st.ItRealValue = n(21)
+    st.StartTime = u(22)
+    st.RSSLim = u(25)
+    st.StartCode = u(26)
+    st.EndCode = u(27)
+    st.StartStack = u(28)
+    st.KStkESP = u(29)
+    st.KStkEIP = u(30)
+    st.Signal = u(31)
+    st.Blocked = u(32)
+    st.SigIgnore = u(33)
+    st.SigCatch = u(34)
+    st.WChan = u(35)
+    st.NSwap = u(36)
+    st.CNSwap = u(37)
+    st.ExitSignal = int(n(38))
+    st.Processor = int(n(39))
+    st.RTPriority = u(40)
+    st.Policy = u(41)
+    st.DelayAcctBlkIOTicks = u(42)
+    st.GuestTime = u(43)
+    st.CGuestTime = n(44)
+    st.StartData = u(45)
+    st.EndData = u(46)
+    st.StartBrk = u(47)
+    st.ArgStart = u(48)
+    st.ArgEnd = u(49)
+    st.EnvStart = u(50)
+    st.EnvEnd = u(51)
+    st.ExitCode = int(n(52))
 
     pageKB := int64(os.Getpagesize() / 1024)
-    vsizeKB = vsize / 1024
-    rssKB = rss * pageKB
+    st.VSizeKB = n(23) / 1024
+    st.RSSKB = n(24) * pageKB
 
     ok = true
     return
-}
\ No newline at end of file
+}
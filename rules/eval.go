@@ -0,0 +1,172 @@
+package rules
+
+import (
+	"sort"
+	"time"
+
+	"sentinel/model"
+)
+
+// State is the lifecycle stage of an alert for one rule on one process.
+type State int
+
+const (
+	// StatePending: the condition holds but not yet for the rule's "for".
+	StatePending State = iota
+	// StateFiring: the condition has held for at least "for".
+	StateFiring
+	// StateResolved: a firing alert whose condition stopped holding or
+	// whose process exited. Resolved alerts are dropped after being reported.
+	StateResolved
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateFiring:
+		return "firing"
+	case StateResolved:
+		return "resolved"
+	}
+	return "unknown"
+}
+
+// Alert is the state of one rule on one process.
+type Alert struct {
+	Rule  *Rule
+	Proc  model.ProcRec // last sample where the condition held
+	State State
+
+	Since      time.Time // first consecutive sample where the rule held
+	FiredAt    time.Time
+	ClearedAt  time.Time // firing, but the condition stopped holding at this time
+	ResolvedAt time.Time
+	Exited     bool // resolved because the process is gone
+}
+
+// Event is a state change worth notifying: an alert started firing or resolved.
+type Event struct {
+	Alert
+}
+
+type evalKey struct {
+	rule string
+	proc model.ProcKey
+}
+
+// Evaluator tracks the alert lifecycle per rule and process. A single sample
+// where the condition does not hold resets a pending alert, so one-tick
+// spikes never reach "for"; a firing alert in that case resolves, unless
+// the rule keeps it firing until the condition has been false long enough.
+type Evaluator struct {
+	rules  []*Rule
+	alerts map[evalKey]*Alert
+}
+
+func NewEvaluator(rules []*Rule) *Evaluator {
+	return &Evaluator{
+		rules:  rules,
+		alerts: make(map[evalKey]*Alert),
+	}
+}
+
+// SetRules replaces the rule set. State of rules whose name is unchanged is
+// kept; alerts of removed rules are dropped without a resolved event.
+func (e *Evaluator) SetRules(rules []*Rule) {
+	byName := make(map[string]*Rule, len(rules))
+	for _, r := range rules {
+		byName[r.Name] = r
+	}
+	for k, a := range e.alerts {
+		r, ok := byName[k.rule]
+		if !ok {
+			delete(e.alerts, k)
+			continue
+		}
+		a.Rule = r
+	}
+	e.rules = rules
+}
+
+// Rules returns the active rule set.
+func (e *Evaluator) Rules() []*Rule {
+	return e.rules
+}
+
+// Active returns a copy of the pending and firing alerts, firing first.
+func (e *Evaluator) Active() []Alert {
+	out := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].State != out[j].State {
+			return out[i].State > out[j].State
+		}
+		return out[i].Since.Before(out[j].Since)
+	})
+	return out
+}
+
+// Step evaluates every rule against one sample and returns the alerts that
+// started firing or resolved. State for processes that are gone is removed.
+func (e *Evaluator) Step(now time.Time, records []model.ProcRec) []Event {
+	var events []Event
+	held := make(map[evalKey]bool)
+	alive := make(map[model.ProcKey]bool, len(records))
+
+	for i := range records {
+		p := &records[i]
+		if !p.Alive {
+			continue
+		}
+		alive[p.Key()] = true
+
+		for _, r := range e.rules {
+			if !r.Matches(p) || !r.Holds(p) {
+				continue
+			}
+
+			k := evalKey{rule: r.Name, proc: p.Key()}
+			held[k] = true
+
+			a, ok := e.alerts[k]
+			if !ok {
+				a = &Alert{Rule: r, State: StatePending, Since: now}
+				e.alerts[k] = a
+			}
+			a.Proc = *p
+			a.ClearedAt = time.Time{}
+
+			if a.State == StatePending && now.Sub(a.Since) >= r.For {
+				a.State = StateFiring
+				a.FiredAt = now
+				events = append(events, Event{Alert: *a})
+			}
+		}
+	}
+
+	for k, a := range e.alerts {
+		if held[k] {
+			continue
+		}
+		if a.State == StateFiring {
+			exited := !alive[k.proc]
+			if !exited && a.Rule.KeepFiring > 0 {
+				if a.ClearedAt.IsZero() {
+					a.ClearedAt = now
+				}
+				if now.Sub(a.ClearedAt) < a.Rule.KeepFiring {
+					continue
+				}
+			}
+			a.State = StateResolved
+			a.ResolvedAt = now
+			a.Exited = exited
+			events = append(events, Event{Alert: *a})
+		}
+		delete(e.alerts, k)
+	}
+	return events
+}
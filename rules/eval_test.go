@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"sentinel/config"
+	"sentinel/model"
+)
+
+func mustRule(t *testing.T, ar config.AlertRule) *Rule {
+	t.Helper()
+	r, err := Compile(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func proc(pid int, cpu float64) model.ProcRec {
+	return model.ProcRec{Pid: pid, StartTime: uint64(pid) * 100, Comm: "worker", CPU: cpu, Alive: true}
+}
+
+// step runs one sample at t0+sec and returns the states of its events.
+func step(e *Evaluator, t0 time.Time, sec int, records ...model.ProcRec) []Event {
+	return e.Step(t0.Add(time.Duration(sec)*time.Second), records)
+}
+
+func TestEvaluatorFor(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	r := mustRule(t, config.AlertRule{Name: "hot", Expr: "cpu > 90", For: config.Duration(10 * time.Second)})
+	e := NewEvaluator([]*Rule{r})
+
+	for _, sec := range []int{0, 5} {
+		if ev := step(e, t0, sec, proc(1, 95)); len(ev) != 0 {
+			t.Fatalf("t=%d: got %d events before \"for\" elapsed", sec, len(ev))
+		}
+	}
+	if a := e.Active(); len(a) != 1 || a[0].State != StatePending {
+		t.Fatalf("active = %+v, want one pending alert", a)
+	}
+
+	// A single sample below the threshold resets the pending alert
+	if ev := step(e, t0, 8, proc(1, 50)); len(ev) != 0 {
+		t.Fatalf("miss produced events: %+v", ev)
+	}
+	if a := e.Active(); len(a) != 0 {
+		t.Fatalf("pending alert survived a miss: %+v", a)
+	}
+
+	for _, sec := range []int{10, 15} {
+		if ev := step(e, t0, sec, proc(1, 95)); len(ev) != 0 {
+			t.Fatalf("t=%d: fired before \"for\" elapsed since the reset", sec)
+		}
+	}
+	ev := step(e, t0, 20, proc(1, 95))
+	if len(ev) != 1 || ev[0].State != StateFiring {
+		t.Fatalf("t=20: events = %+v, want one firing", ev)
+	}
+	if want := t0.Add(10 * time.Second); !ev[0].Since.Equal(want) {
+		t.Errorf("since = %v, want %v", ev[0].Since, want)
+	}
+
+	// Still holding: no repeated firing
+	if ev := step(e, t0, 25, proc(1, 95)); len(ev) != 0 {
+		t.Fatalf("t=25: firing repeated: %+v", ev)
+	}
+
+	ev = step(e, t0, 30, proc(1, 10))
+	if len(ev) != 1 || ev[0].State != StateResolved || ev[0].Exited {
+		t.Fatalf("t=30: events = %+v, want one resolved, not exited", ev)
+	}
+	if len(e.Active()) != 0 {
+		t.Errorf("resolved alert still active")
+	}
+}
+
+func TestEvaluatorFiresImmediatelyWithoutFor(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	e := NewEvaluator([]*Rule{mustRule(t, config.AlertRule{Name: "hot", Expr: "cpu > 90"})})
+
+	ev := step(e, t0, 0, proc(1, 95))
+	if len(ev) != 1 || ev[0].State != StateFiring {
+		t.Fatalf("events = %+v, want one firing", ev)
+	}
+}
+
+func TestEvaluatorProcessExit(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	r := mustRule(t, config.AlertRule{Name: "hot", Expr: "cpu > 90", KeepFiringFor: config.Duration(time.Minute)})
+	e := NewEvaluator([]*Rule{r})
+
+	step(e, t0, 0, proc(1, 95), proc(2, 95))
+	ev := step(e, t0, 1, proc(2, 95))
+	if len(ev) != 1 || ev[0].Proc.Pid != 1 || !ev[0].Exited {
+		t.Fatalf("events = %+v, want pid 1 resolved as exited despite keep_firing_for", ev)
+	}
+}
+
+func TestEvaluatorKeepFiring(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	r := mustRule(t, config.AlertRule{Name: "hot", Expr: "cpu > 90", KeepFiringFor: config.Duration(30 * time.Second)})
+	e := NewEvaluator([]*Rule{r})
+
+	if ev := step(e, t0, 0, proc(1, 95)); len(ev) != 1 {
+		t.Fatalf("no firing event")
+	}
+
+	// Oscillating around the threshold keeps a single firing alert
+	for sec := 1; sec <= 60; sec++ {
+		cpu := 95.0
+		if sec%2 == 1 {
+			cpu = 85
+		}
+		if ev := step(e, t0, sec, proc(1, cpu)); len(ev) != 0 {
+			t.Fatalf("t=%d: flapped: %+v", sec, ev)
+		}
+	}
+
+	// Resolves once the condition has been false for keep_firing_for
+	for sec := 61; sec < 90; sec++ {
+		if ev := step(e, t0, sec, proc(1, 50)); len(ev) != 0 {
+			t.Fatalf("t=%d: resolved early", sec)
+		}
+	}
+	ev := step(e, t0, 91, proc(1, 50))
+	if len(ev) != 1 || ev[0].State != StateResolved {
+		t.Fatalf("events = %+v, want one resolved", ev)
+	}
+}
+
+func TestEvaluatorMatch(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	r := mustRule(t, config.AlertRule{Name: "hot", Expr: "cpu > 90", Match: config.RuleMatch{Comm: "^db"}})
+	e := NewEvaluator([]*Rule{r})
+
+	worker := proc(1, 95)
+	db := proc(2, 95)
+	db.Comm = "dbserver"
+	ev := step(e, t0, 0, worker, db)
+	if len(ev) != 1 || ev[0].Proc.Pid != 2 {
+		t.Fatalf("events = %+v, want only pid 2", ev)
+	}
+}
+
+func TestBuiltinRules(t *testing.T) {
+	cfg := config.Default()
+	compiled, errs := FromConfig(cfg)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, r := range compiled {
+		if r.For != defaultBuiltinFor || r.KeepFiring != builtinKeepFiring {
+			t.Errorf("%s: for %s keep %s, want %s and %s", r.Name, r.For, r.KeepFiring, defaultBuiltinFor, builtinKeepFiring)
+		}
+	}
+
+	zero := config.Duration(0)
+	cfg.BuiltinFor = &zero
+	compiled, _ = FromConfig(cfg)
+	if len(compiled) == 0 || compiled[0].For != 0 {
+		t.Errorf("builtin_for 0 not applied")
+	}
+}
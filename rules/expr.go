@@ -0,0 +1,393 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expr is a compiled expression over process metrics. Comparisons and
+// logical operators yield 1 (true) or 0 (false).
+//
+// Grammar:
+//
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | cmp
+//	cmp     = sum [ ("<" | "<=" | ">" | ">=" | "==" | "!=") sum ]
+//	sum     = prod { ("+" | "-") prod }
+//	prod    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | metric | "(" or ")"
+type Expr struct {
+	src     string
+	eval    func(m Metrics) float64
+	metrics map[string]bool // names referenced
+
+	// set when the expression is a single "metric op number" comparison
+	metric    string
+	op        string
+	threshold float64
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval returns the numeric value of the expression.
+func (e *Expr) Eval(m Metrics) float64 {
+	return e.eval(m)
+}
+
+// True reports whether the expression evaluates to a non-zero value.
+func (e *Expr) True(m Metrics) bool {
+	return e.eval(m) != 0
+}
+
+// Uses reports whether the expression references metric.
+func (e *Expr) Uses(metric string) bool {
+	return e.metrics[metric]
+}
+
+// Threshold returns the metric, operator and constant of an expression that
+// is a single comparison such as "cpu >= 80". ok is false otherwise.
+func (e *Expr) Threshold() (metric, op string, threshold float64, ok bool) {
+	return e.metric, e.op, e.threshold, e.metric != ""
+}
+
+// ParseExpr compiles src. Unknown metric names are rejected here so that a
+// typo is reported when the config is loaded, not silently evaluated as 0.
+func ParseExpr(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, metrics: make(map[string]bool)}
+	fn, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	e := &Expr{src: src, eval: fn, metrics: p.metrics}
+	if len(toks) == 4 && toks[0].kind == tokIdent && toks[1].kind == tokOp && toks[2].kind == tokNum {
+		switch toks[1].text {
+		case "<", "<=", ">", ">=", "==", "!=":
+			e.metric = strings.ToLower(toks[0].text)
+			e.op = toks[1].text
+			e.threshold = toks[2].num
+		}
+	}
+	return e, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  float64
+	pos  int
+}
+
+// twoCharOps must be tried before single-character ones.
+var twoCharOps = []string{"&&", "||", "<=", ">=", "==", "!="}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			v, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[start:i], start)
+			}
+			toks = append(toks, token{kind: tokNum, text: src[start:i], num: v, pos: start})
+
+		case isIdentByte(src[i]) && !unicode.IsDigit(c):
+			start := i
+			for i < len(src) && isIdentByte(src[i]) {
+				i++
+			}
+			word := src[start:i]
+			switch strings.ToLower(word) {
+			case "and":
+				toks = append(toks, token{kind: tokOp, text: "&&", pos: start})
+			case "or":
+				toks = append(toks, token{kind: tokOp, text: "||", pos: start})
+			case "not":
+				toks = append(toks, token{kind: tokOp, text: "!", pos: start})
+			default:
+				toks = append(toks, token{kind: tokIdent, text: word, pos: start})
+			}
+
+		default:
+			matched := false
+			for _, op := range twoCharOps {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, token{kind: tokOp, text: op, pos: i})
+					i += 2
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if strings.ContainsRune("<>!+-*/", c) {
+				toks = append(toks, token{kind: tokOp, text: string(c), pos: i})
+				i++
+				continue
+			}
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, text: "end of expression", pos: len(src)})
+	return toks, nil
+}
+
+// isIdentByte reports whether b may appear in a metric name. Names are
+// ASCII, so lexing bytewise never splits a multi-byte character.
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+type evalFn func(m Metrics) float64
+
+type parser struct {
+	toks    []token
+	pos     int
+	metrics map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func boolVal(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) parseOr() (evalFn, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(m Metrics) float64 { return boolVal(l(m) != 0 || right(m) != 0) }
+	}
+}
+
+func (p *parser) parseAnd() (evalFn, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(m Metrics) float64 { return boolVal(l(m) != 0 && right(m) != 0) }
+	}
+}
+
+func (p *parser) parseNot() (evalFn, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(m Metrics) float64 { return boolVal(inner(m) == 0) }, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (evalFn, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "<":
+		return func(m Metrics) float64 { return boolVal(left(m) < right(m)) }, nil
+	case "<=":
+		return func(m Metrics) float64 { return boolVal(left(m) <= right(m)) }, nil
+	case ">":
+		return func(m Metrics) float64 { return boolVal(left(m) > right(m)) }, nil
+	case ">=":
+		return func(m Metrics) float64 { return boolVal(left(m) >= right(m)) }, nil
+	case "==":
+		return func(m Metrics) float64 { return boolVal(left(m) == right(m)) }, nil
+	default:
+		return func(m Metrics) float64 { return boolVal(left(m) != right(m)) }, nil
+	}
+}
+
+func (p *parser) parseSum() (evalFn, error) {
+	left, err := p.parseProd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(m Metrics) float64 { return l(m) + right(m) }
+		} else {
+			left = func(m Metrics) float64 { return l(m) - right(m) }
+		}
+	}
+}
+
+func (p *parser) parseProd() (evalFn, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "*" {
+			left = func(m Metrics) float64 { return l(m) * right(m) }
+		} else {
+			left = func(m Metrics) float64 {
+				d := right(m)
+				if d == 0 {
+					return 0
+				}
+				return l(m) / d
+			}
+		}
+	}
+}
+
+func (p *parser) parseUnary() (evalFn, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(m Metrics) float64 { return -inner(m) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (evalFn, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		v := t.num
+		return func(Metrics) float64 { return v }, nil
+
+	case tokIdent:
+		name := strings.ToLower(t.text)
+		if _, ok := metricDefs[name]; !ok {
+			return nil, fmt.Errorf("unknown metric %q at offset %d (known: %s)",
+				t.text, t.pos, strings.Join(MetricNames(), ", "))
+		}
+		p.metrics[name] = true
+		return func(m Metrics) float64 { return m.Get(name) }, nil
+
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at offset %d, got %q", closing.pos, closing.text)
+		}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
@@ -0,0 +1,139 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"sentinel/config"
+	"sentinel/model"
+)
+
+func TestExprEval(t *testing.T) {
+	rec := &model.ProcRec{CPU: 50, PMem: 10, Threads: 4, RSSKB: 2048}
+	m := Metrics{Rec: rec}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		// arithmetic precedence and associativity
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"10 - 2 - 3 == 5", true},
+		{"8 / 2 / 2 == 2", true},
+		{"-2 * 3 + 10 == 4", true},
+		{"- -3 == 3", true},
+		{"cpu / 0 == 0", true},
+		{"rss_mb == 2", true},
+
+		// && binds tighter than ||, comparisons tighter than both
+		{"cpu > 40 || mem > 50 && threads > 10", true},
+		{"(cpu > 40 || mem > 50) && threads > 10", false},
+		{"cpu > 60 || mem > 5 && threads == 4", true},
+
+		// ! applies to the whole comparison
+		{"!cpu > 60", true},
+		{"!(cpu > 40)", false},
+		{"!!(cpu > 40)", true},
+
+		// word aliases, case-insensitive
+		{"cpu >= 50 and mem < 20", true},
+		{"CPU >= 50 AND MEM < 20", true},
+		{"cpu > 90 or mem == 10", true},
+		{"not cpu > 60", true},
+		{"cpu > 10 and not (mem > 5 or threads < 2)", false},
+
+		{"cpu != 50", false},
+		{"cpu <= 50", true},
+		{"cpu < 50", false},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.src, err)
+			continue
+		}
+		if got := e.True(m); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // substring of the error
+	}{
+		{"", "unexpected"},
+		{"cpu >", "unexpected"},
+		{"cpu > 1)", "unexpected \")\""},
+		{"(cpu > 1", "expected ')'"},
+		{"cpu >> 1", "unexpected \">\""},
+		{"cpu > 1..2", "invalid number"},
+		{"cpu > 1 mem", "unexpected \"mem\""},
+		{"cpus > 1", "unknown metric \"cpus\""},
+		{"cpu > 1 && memory > 2", "unknown metric \"memory\""},
+		{"cpu % 2", "unexpected character"},
+		{"cpu = 1", "unexpected character"},
+		{"&&", "unexpected"},
+		{"not", "unexpected"},
+		{"()", "unexpected"},
+		{"é > 1", "unexpected character 'é'"},
+		{"cpu > \x00", "unexpected character"},
+		{strings.Repeat("(", 50), "unexpected"},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if err == nil {
+			t.Errorf("ParseExpr(%q) = %v, want error", tt.src, e)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseExpr(%q) error %q, want it to contain %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestExprThreshold(t *testing.T) {
+	tests := []struct {
+		src       string
+		metric    string
+		op        string
+		threshold float64
+		ok        bool
+	}{
+		{"cpu >= 80", "cpu", ">=", 80, true},
+		{"MEM < 2.5", "mem", "<", 2.5, true},
+		{"cpu >= 80 && mem > 1", "", "", 0, false},
+		{"80 <= cpu", "", "", 0, false},
+		{"threads", "", "", 0, false},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q): %v", tt.src, err)
+		}
+		metric, op, threshold, ok := e.Threshold()
+		if metric != tt.metric || op != tt.op || threshold != tt.threshold || ok != tt.ok {
+			t.Errorf("%q.Threshold() = %q, %q, %g, %v, want %q, %q, %g, %v",
+				tt.src, metric, op, threshold, ok, tt.metric, tt.op, tt.threshold, tt.ok)
+		}
+	}
+}
+
+func TestExprUses(t *testing.T) {
+	e, err := ParseExpr("cpu > 50 && (FDS > 1000 || threads > 100)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for metric, want := range map[string]bool{"cpu": true, "fds": true, "threads": true, "mem": false} {
+		if e.Uses(metric) != want {
+			t.Errorf("Uses(%q) = %v, want %v", metric, !want, want)
+		}
+	}
+
+	r := mustRule(t, config.AlertRule{Name: "fd-leak", Expr: "fds > 1000"})
+	if !UseMetric([]*Rule{r}, "fds") || UseMetric([]*Rule{r}, "cpu") {
+		t.Errorf("UseMetric does not follow the rule expressions")
+	}
+}
@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"sort"
+
+	"sentinel/model"
+)
+
+// metricDefs maps the names usable in expressions to their value on a record.
+var metricDefs = map[string]func(r *model.ProcRec) float64{
+	"cpu":        func(r *model.ProcRec) float64 { return r.CPU },
+	"mem":        func(r *model.ProcRec) float64 { return r.PMem },
+	"rss_kb":     func(r *model.ProcRec) float64 { return float64(r.RSSKB) },
+	"rss_mb":     func(r *model.ProcRec) float64 { return float64(r.RSSKB) / 1024 },
+	"vsize_kb":   func(r *model.ProcRec) float64 { return float64(r.VSizeKB) },
+	"vsize_mb":   func(r *model.ProcRec) float64 { return float64(r.VSizeKB) / 1024 },
+	"read_bps":   func(r *model.ProcRec) float64 { return r.ReadBps },
+	"write_bps":  func(r *model.ProcRec) float64 { return r.WriteBps },
+	"read_mbps":  func(r *model.ProcRec) float64 { return r.ReadBps / 1024 / 1024 },
+	"write_mbps": func(r *model.ProcRec) float64 { return r.WriteBps / 1024 / 1024 },
+	"threads":    func(r *model.ProcRec) float64 { return float64(r.Threads) },
+	"fds":        func(r *model.ProcRec) float64 { return float64(r.FDs) },
+	"nice":       func(r *model.ProcRec) float64 { return float64(r.Nice) },
+	"prio":       func(r *model.ProcRec) float64 { return float64(r.Prio) },
+	"minflt":     func(r *model.ProcRec) float64 { return float64(r.MinFlt) },
+	"majflt":     func(r *model.ProcRec) float64 { return float64(r.MajFlt) },
+	"pid":        func(r *model.ProcRec) float64 { return float64(r.Pid) },
+	"ppid":       func(r *model.ProcRec) float64 { return float64(r.PPid) },
+	"uid":        func(r *model.ProcRec) float64 { return float64(r.Uid) },
+}
+
+// Metrics exposes the values of one process record to expressions.
+type Metrics struct {
+	Rec *model.ProcRec
+}
+
+// Get returns the named metric, or 0 if unknown.
+func (m Metrics) Get(name string) float64 {
+	if f, ok := metricDefs[name]; ok && m.Rec != nil {
+		return f(m.Rec)
+	}
+	return 0
+}
+
+// MetricNames lists the metrics available in expressions, sorted.
+func MetricNames() []string {
+	names := make([]string, 0, len(metricDefs))
+	for name := range metricDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"sentinel/config"
+	"sentinel/model"
+)
+
+// Rule is a compiled config.AlertRule.
+type Rule struct {
+	Name        string
+	Description string
+	Severity    config.Severity
+	For         time.Duration
+	KeepFiring  time.Duration
+	Expr        *Expr
+
+	comm    *regexp.Regexp
+	cmdline *regexp.Regexp
+	user    string
+}
+
+// Compile validates and compiles a rule from the config.
+func Compile(ar config.AlertRule) (*Rule, error) {
+	if ar.Name == "" {
+		return nil, fmt.Errorf("rule has no name")
+	}
+	if ar.For < 0 {
+		return nil, fmt.Errorf("rule %q: negative \"for\" duration", ar.Name)
+	}
+	if ar.KeepFiringFor < 0 {
+		return nil, fmt.Errorf("rule %q: negative \"keep_firing_for\" duration", ar.Name)
+	}
+
+	severity := ar.Severity
+	if severity == "" {
+		severity = config.SeverityWarning
+	}
+	if !severity.Valid() {
+		return nil, fmt.Errorf("rule %q: unknown severity %q", ar.Name, ar.Severity)
+	}
+
+	expr, err := ParseExpr(ar.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: expr: %w", ar.Name, err)
+	}
+
+	r := &Rule{
+		Name:        ar.Name,
+		Description: ar.Description,
+		Severity:    severity,
+		For:         time.Duration(ar.For),
+		KeepFiring:  time.Duration(ar.KeepFiringFor),
+		Expr:        expr,
+		user:        ar.Match.User,
+	}
+
+	if ar.Match.Comm != "" {
+		if r.comm, err = regexp.Compile(ar.Match.Comm); err != nil {
+			return nil, fmt.Errorf("rule %q: match.comm: %w", ar.Name, err)
+		}
+	}
+	if ar.Match.Cmdline != "" {
+		if r.cmdline, err = regexp.Compile(ar.Match.Cmdline); err != nil {
+			return nil, fmt.Errorf("rule %q: match.cmdline: %w", ar.Name, err)
+		}
+	}
+	return r, nil
+}
+
+// Matches reports whether the rule's selector applies to the process.
+func (r *Rule) Matches(p *model.ProcRec) bool {
+	if r.user != "" && p.User != r.user {
+		return false
+	}
+	if r.comm != nil && !r.comm.MatchString(p.Comm) {
+		return false
+	}
+	if r.cmdline != nil && !r.cmdline.MatchString(p.Cmd) {
+		return false
+	}
+	return true
+}
+
+// Holds reports whether the rule's expression is true for the process.
+func (r *Rule) Holds(p *model.ProcRec) bool {
+	return r.Expr.True(Metrics{Rec: p})
+}
+
+// FromConfig compiles the built-in threshold rules followed by the
+// configured ones. Invalid rules are skipped and reported in errs.
+func FromConfig(cfg *config.SentinelConfig) (compiled []*Rule, errs []error) {
+	for _, ar := range builtinRules(cfg) {
+		r, err := Compile(ar)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		compiled = append(compiled, r)
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range compiled {
+		seen[r.Name] = true
+	}
+	for _, ar := range cfg.Rules {
+		if seen[ar.Name] {
+			errs = append(errs, fmt.Errorf("rule %q: duplicate name", ar.Name))
+			continue
+		}
+		r, err := Compile(ar)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		seen[r.Name] = true
+		compiled = append(compiled, r)
+	}
+	return compiled, errs
+}
+
+// UseMetric reports whether any of rules references metric, so that the
+// sampler can skip collecting metrics that are costly and unused.
+func UseMetric(rules []*Rule, metric string) bool {
+	for _, r := range rules {
+		if r.Expr.Uses(metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// Defaults of the built-in threshold rules. Keeping them firing for a
+// minute replaces the old 60s notification cooldown.
+const (
+	defaultBuiltinFor = 30 * time.Second
+	builtinKeepFiring = time.Minute
+)
+
+// BuiltinTiming returns how long a built-in threshold must hold before its
+// alert fires and how long it keeps firing once it no longer holds. The
+// daemon applies the same timing to its system-wide alerts.
+func BuiltinTiming(cfg *config.SentinelConfig) (forDur, keepFiring time.Duration) {
+	forDur = defaultBuiltinFor
+	if cfg.BuiltinFor != nil {
+		forDur = time.Duration(*cfg.BuiltinFor)
+	}
+	return forDur, builtinKeepFiring
+}
+
+// builtinRules turns the global per-process thresholds into rules. A zero
+// threshold disables the CPU/MEM rules like it does for I/O.
+func builtinRules(cfg *config.SentinelConfig) []config.AlertRule {
+	forDur, keepFiring := BuiltinTiming(cfg)
+
+	var out []config.AlertRule
+	add := func(name, metric, label string, threshold float64) {
+		if threshold <= 0 {
+			return
+		}
+		out = append(out, config.AlertRule{
+			Name:          name,
+			Expr:          fmt.Sprintf("%s >= %g", metric, threshold),
+			For:           config.Duration(forDur),
+			KeepFiringFor: config.Duration(keepFiring),
+			Description:   label,
+		})
+	}
+	add("high-cpu", "cpu", "High CPU", cfg.CPUThreshold)
+	add("high-mem", "mem", "High Memory", cfg.MemThreshold)
+	add("high-disk-read", "read_mbps", "High Disk Read", cfg.IOReadThresholdMB)
+	add("high-disk-write", "write_mbps", "High Disk Write", cfg.IOWriteThresholdMB)
+	return out
+}
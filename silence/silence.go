@@ -0,0 +1,232 @@
+// Package silence decides whether an alert falls under a configured silence
+// or maintenance window.
+package silence
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"sentinel/alert"
+	"sentinel/config"
+)
+
+// weekdays maps the accepted day names, full or abbreviated, to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// matcher selects alerts by rule name and process, like config.RuleMatch.
+type matcher struct {
+	rules   []string
+	comm    *regexp.Regexp
+	cmdline *regexp.Regexp
+	user    string
+}
+
+func newMatcher(rules []string, m config.RuleMatch) (matcher, error) {
+	out := matcher{rules: rules, user: m.User}
+	var err error
+	if m.Comm != "" {
+		if out.comm, err = regexp.Compile(m.Comm); err != nil {
+			return out, fmt.Errorf("match.comm: %w", err)
+		}
+	}
+	if m.Cmdline != "" {
+		if out.cmdline, err = regexp.Compile(m.Cmdline); err != nil {
+			return out, fmt.Errorf("match.cmdline: %w", err)
+		}
+	}
+	return out, nil
+}
+
+func (m *matcher) match(a *alert.Alert) bool {
+	if len(m.rules) > 0 && !slices.Contains(m.rules, a.Rule) {
+		return false
+	}
+	if m.comm == nil && m.cmdline == nil && m.user == "" {
+		return true
+	}
+	if a.Pid == 0 {
+		return false
+	}
+	if m.user != "" && a.User != m.user {
+		return false
+	}
+	if m.comm != nil && !m.comm.MatchString(a.Comm) {
+		return false
+	}
+	if m.cmdline != nil && !m.cmdline.MatchString(a.Cmd) {
+		return false
+	}
+	return true
+}
+
+type silence struct {
+	matcher
+	cfg config.Silence
+}
+
+type window struct {
+	matcher
+	cfg   config.MaintenanceWindow
+	days  map[time.Weekday]bool // empty: every day
+	start time.Duration         // offset from midnight
+}
+
+// Set is the compiled silences and maintenance windows of a config.
+type Set struct {
+	silences []silence
+	windows  []window
+}
+
+// Compile builds the set from cfg. Invalid entries are skipped and reported.
+func Compile(cfg *config.SentinelConfig) (*Set, []error) {
+	s := &Set{}
+	var errs []error
+
+	for _, c := range cfg.Silences {
+		m, err := newMatcher(c.Rules, c.Match)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("silence %q: %w", c.ID, err))
+			continue
+		}
+		if !c.End.After(c.Start) {
+			errs = append(errs, fmt.Errorf("silence %q: end is not after start", c.ID))
+			continue
+		}
+		s.silences = append(s.silences, silence{matcher: m, cfg: c})
+	}
+
+	for _, c := range cfg.Maintenance {
+		w, err := compileWindow(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("maintenance window %q: %w", c.Name, err))
+			continue
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, errs
+}
+
+func compileWindow(c config.MaintenanceWindow) (window, error) {
+	m, err := newMatcher(c.Rules, c.Match)
+	if err != nil {
+		return window{}, err
+	}
+
+	start, err := ParseClock(c.Start)
+	if err != nil {
+		return window{}, err
+	}
+	if c.Duration <= 0 || time.Duration(c.Duration) > 7*24*time.Hour {
+		return window{}, fmt.Errorf("duration must be between 0 and 7 days")
+	}
+
+	w := window{matcher: m, cfg: c, start: start, days: make(map[time.Weekday]bool)}
+	for _, d := range c.Days {
+		wd, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return window{}, fmt.Errorf("unknown day %q, want mon..sun or monday..sunday", d)
+		}
+		w.days[wd] = true
+	}
+	return w, nil
+}
+
+// ParseClock parses "HH:MM" into an offset from midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// occurrence returns the window occurrence containing now, if any. Windows
+// started on the previous days may still be running.
+func (w *window) occurrence(now time.Time) (start, end time.Time, ok bool) {
+	days := int(time.Duration(w.cfg.Duration)/(24*time.Hour)) + 1
+	for back := 0; back <= days; back++ {
+		day := now.AddDate(0, 0, -back)
+		y, m, d := day.Date()
+		start = time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(w.start)
+		end = start.Add(time.Duration(w.cfg.Duration))
+
+		if len(w.days) > 0 && !w.days[start.Weekday()] {
+			continue
+		}
+		if !now.Before(start) && now.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// next returns the next start of the window after now.
+func (w *window) next(now time.Time) time.Time {
+	for ahead := 0; ahead <= 7; ahead++ {
+		day := now.AddDate(0, 0, ahead)
+		y, m, d := day.Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(w.start)
+		if start.After(now) && (len(w.days) == 0 || w.days[start.Weekday()]) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// Silenced returns a description of the silence or maintenance window that
+// covers the alert at now, or "" if it should be sent.
+func (s *Set) Silenced(a *alert.Alert, now time.Time) string {
+	if s == nil {
+		return ""
+	}
+
+	for i := range s.silences {
+		sl := &s.silences[i]
+		if now.Before(sl.cfg.Start) || !now.Before(sl.cfg.End) {
+			continue
+		}
+		if sl.match(a) {
+			return "silence " + sl.cfg.ID
+		}
+	}
+	for i := range s.windows {
+		w := &s.windows[i]
+		if _, _, ok := w.occurrence(now); ok && w.match(a) {
+			return "maintenance " + w.cfg.Name
+		}
+	}
+	return ""
+}
+
+// WindowStatus describes a maintenance window at a point in time.
+type WindowStatus struct {
+	Window config.MaintenanceWindow
+	Active bool
+	Start  time.Time // current occurrence if active, else the next one
+	End    time.Time
+}
+
+// Windows returns the state of every maintenance window at now.
+func (s *Set) Windows(now time.Time) []WindowStatus {
+	out := make([]WindowStatus, 0, len(s.windows))
+	for i := range s.windows {
+		w := &s.windows[i]
+		st := WindowStatus{Window: w.cfg}
+		if start, end, ok := w.occurrence(now); ok {
+			st.Active, st.Start, st.End = true, start, end
+		} else if next := w.next(now); !next.IsZero() {
+			st.Start, st.End = next, next.Add(time.Duration(w.cfg.Duration))
+		}
+		out = append(out, st)
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package silence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sentinel/alert"
+	"sentinel/config"
+)
+
+func TestWindowDays(t *testing.T) {
+	tests := []struct {
+		day string
+		ok  bool
+	}{
+		{"sun", true},
+		{"Mon", true},
+		{"tuesday", true},
+		{"SATURDAY", true},
+		{"monxyz", false},
+		{"sunflower", false},
+		{"mo", false},
+		{"", false},
+		{"K", false}, // Kelvin sign, lowercases to a shorter "k"
+		{"Kaaa", false},
+	}
+	for _, tt := range tests {
+		cfg := &config.SentinelConfig{Maintenance: []config.MaintenanceWindow{{
+			Name:     "w",
+			Days:     []string{tt.day},
+			Start:    "02:00",
+			Duration: config.Duration(time.Hour),
+		}}}
+		_, errs := Compile(cfg)
+		if ok := len(errs) == 0; ok != tt.ok {
+			t.Errorf("day %q: errs %v, want ok=%v", tt.day, errs, tt.ok)
+		}
+		if !tt.ok && len(errs) > 0 && !strings.Contains(errs[0].Error(), "unknown day") {
+			t.Errorf("day %q: error %q", tt.day, errs[0])
+		}
+	}
+}
+
+func mustCompile(t *testing.T, cfg *config.SentinelConfig) *Set {
+	t.Helper()
+	s, errs := Compile(cfg)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return s
+}
+
+func TestWindowOccurrence(t *testing.T) {
+	loc := time.FixedZone("test", 2*60*60)
+	at := func(day int, clock string) time.Time {
+		c, _ := ParseClock(clock)
+		// 2024-06-02 is a Sunday
+		return time.Date(2024, 6, 2+day, 0, 0, 0, 0, loc).Add(c)
+	}
+
+	tests := []struct {
+		name  string
+		days  []string
+		start string
+		dur   time.Duration
+		now   time.Time
+		ok    bool
+		from  time.Time
+	}{
+		{"daily, before midnight", nil, "22:00", 4 * time.Hour, at(0, "23:30"), true, at(0, "22:00")},
+		{"daily, after midnight", nil, "22:00", 4 * time.Hour, at(1, "01:59"), true, at(0, "22:00")},
+		{"daily, at the end", nil, "22:00", 4 * time.Hour, at(1, "02:00"), false, time.Time{}},
+		{"daily, before the start", nil, "22:00", 4 * time.Hour, at(1, "21:59"), false, time.Time{}},
+		{"sunday, into monday", []string{"sun"}, "23:00", 3 * time.Hour, at(1, "01:00"), true, at(0, "23:00")},
+		{"sunday, on monday night", []string{"sun"}, "23:00", 3 * time.Hour, at(1, "23:30"), false, time.Time{}},
+		{"saturday, two days long", []string{"sat"}, "20:00", 48 * time.Hour, at(1, "19:00"), true, at(-1, "20:00")},
+		{"saturday, after two days", []string{"sat"}, "20:00", 48 * time.Hour, at(1, "20:00"), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		w, err := compileWindow(config.MaintenanceWindow{
+			Name: "w", Days: tt.days, Start: tt.start, Duration: config.Duration(tt.dur),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, end, ok := w.occurrence(tt.now)
+		if ok != tt.ok || !start.Equal(tt.from) {
+			t.Errorf("%s: occurrence = %v, %v, want %v from %v", tt.name, start, ok, tt.ok, tt.from)
+		}
+		if ok && !end.Equal(start.Add(tt.dur)) {
+			t.Errorf("%s: ends %v", tt.name, end)
+		}
+	}
+}
+
+func TestSilenced(t *testing.T) {
+	now := time.Date(2024, 6, 3, 1, 0, 0, 0, time.Local) // a Monday
+	set := mustCompile(t, &config.SentinelConfig{
+		Silences: []config.Silence{
+			{ID: "cpu", Rules: []string{"high-cpu"}, Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+			{ID: "old", Start: now.Add(-2 * time.Hour), End: now},
+			{ID: "later", Start: now.Add(time.Minute), End: now.Add(time.Hour)},
+		},
+		Maintenance: []config.MaintenanceWindow{
+			{Name: "backups", Days: []string{"sun"}, Start: "23:00", Duration: config.Duration(3 * time.Hour),
+				Match: config.RuleMatch{Comm: "^pg_dump$"}},
+		},
+	})
+
+	tests := []struct {
+		a    alert.Alert
+		want string
+	}{
+		{alert.Alert{Rule: "high-cpu", Pid: 1, Comm: "worker"}, "silence cpu"},
+		{alert.Alert{Rule: "high-mem", Pid: 1, Comm: "worker"}, ""},
+		{alert.Alert{Rule: "high-mem", Pid: 2, Comm: "pg_dump"}, "maintenance backups"},
+		{alert.Alert{Rule: "system-mem"}, ""}, // a process match never covers system alerts
+	}
+	for _, tt := range tests {
+		if got := set.Silenced(&tt.a, now); got != tt.want {
+			t.Errorf("%s on %s: Silenced = %q, want %q", tt.a.Rule, tt.a.Comm, got, tt.want)
+		}
+	}
+
+	// The window started on Sunday is over on Monday night
+	a := alert.Alert{Rule: "high-mem", Pid: 2, Comm: "pg_dump"}
+	if got := set.Silenced(&a, now.Add(22*time.Hour)); got != "" {
+		t.Errorf("Monday 23:00: Silenced = %q", got)
+	}
+	if got := (*Set)(nil).Silenced(&a, now); got != "" {
+		t.Errorf("nil set: Silenced = %q", got)
+	}
+}
@@ -0,0 +1,385 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/model"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cell is the data a column renders for one row. In tree mode, collapsed
+// nodes carry subtree totals in cpu/mem/rssKB and a label prefix.
+type cell struct {
+	rec   model.ProcRec
+	cpu   float64
+	mem   float64
+	rssKB int64
+	label string // program name, with tree guides in tree mode
+}
+
+// columnDef describes a table column. Value returns plain text, which is
+// truncated to the column width before Style is applied.
+type columnDef struct {
+	ID       string
+	Title    string
+	Width    int
+	Flex     bool // takes the remaining terminal width
+	Sortable bool
+	Sort     model.SortColumn
+	Value    func(m *Model, c cell) string
+	Style    func(c cell, s string) string
+}
+
+// columnRegistry lists every column the table can show, in the order they
+// are offered in the settings screen.
+var columnRegistry = []columnDef{
+	{ID: "pid", Title: "PID", Width: 7, Sortable: true, Sort: model.SortByPID,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Pid) }},
+	{ID: "ppid", Title: "PPID", Width: 7,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.PPid) }},
+	{ID: "user", Title: "USER", Width: 10, Sortable: true, Sort: model.SortByUSER,
+		Value: func(_ *Model, c cell) string { return c.rec.User }},
+	{ID: "uid", Title: "UID", Width: 6,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Uid) }},
+	{ID: "program", Title: "PROGRAM", Width: 15,
+		Value: func(_ *Model, c cell) string { return c.label }},
+	{ID: "cpu", Title: "%CPU", Width: 7, Sortable: true, Sort: model.SortByCPUCol,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%.1f", c.cpu) },
+		Style: func(c cell, s string) string { return thresholdStyle(c.cpu, 20, 50, s) }},
+	{ID: "mem", Title: "%MEM", Width: 7, Sortable: true, Sort: model.SortByMEM,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%.1f", c.mem) },
+		Style: func(c cell, s string) string { return thresholdStyle(c.mem, 5, 10, s) }},
+	{ID: "vsize", Title: "VSIZE", Width: 9, Sortable: true, Sort: model.SortByVSIZE,
+		Value: func(_ *Model, c cell) string { return FormatKB(c.rec.VSizeKB) }},
+	{ID: "rss", Title: "RSS", Width: 9, Sortable: true, Sort: model.SortByRSS,
+		Value: func(_ *Model, c cell) string { return FormatKB(c.rssKB) }},
+	{ID: "read", Title: "READ/s", Width: 9, Sortable: true, Sort: model.SortByREAD,
+		Value: func(m *Model, c cell) string { return m.formatIO(c.rec, c.rec.ReadBps) }},
+	{ID: "write", Title: "WRITE/s", Width: 9, Sortable: true, Sort: model.SortByWRITE,
+		Value: func(m *Model, c cell) string { return m.formatIO(c.rec, c.rec.WriteBps) }},
+	{ID: "state", Title: "S", Width: 3,
+		Value: func(_ *Model, c cell) string { return string(c.rec.State) }},
+	{ID: "prio", Title: "PRI", Width: 4,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Prio) }},
+	{ID: "nice", Title: "NI", Width: 4,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Nice) }},
+	{ID: "threads", Title: "THR", Width: 5,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Threads) }},
+	{ID: "processor", Title: "CPU#", Width: 5,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.Processor) }},
+	{ID: "start", Title: "START", Width: 8,
+		Value: func(m *Model, c cell) string { return m.formatStart(c.rec) }},
+	{ID: "time", Title: "TIME+", Width: 9, Sortable: true, Sort: model.SortByTIME,
+		Value: func(_ *Model, c cell) string { return FormatTimeTicks(c.rec.CurProcTime, model.DefaultHZ) }},
+	{ID: "minflt", Title: "MINFLT", Width: 9,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.MinFlt) }},
+	{ID: "majflt", Title: "MAJFLT", Width: 8,
+		Value: func(_ *Model, c cell) string { return fmt.Sprintf("%d", c.rec.MajFlt) }},
+	{ID: "fds", Title: "FDS", Width: 5,
+		Value: func(_ *Model, c cell) string {
+			if c.rec.FDs < 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%d", c.rec.FDs)
+		}},
+	{ID: "cgroup", Title: "CGROUP", Width: 24,
+		Value: func(_ *Model, c cell) string { return c.rec.Cgroup }},
+	{ID: "command", Title: "COMMAND", Width: 45, Flex: true,
+		Value: func(_ *Model, c cell) string { return commandArgs(c.rec) }},
+}
+
+// defaultColumns is the layout used when the config has none.
+var defaultColumns = []string{
+	"pid", "user", "program", "cpu", "mem", "vsize", "rss",
+	"read", "write", "state", "time", "command",
+}
+
+// minFlexWidth is the narrowest a flexible column is allowed to get.
+const minFlexWidth = 10
+
+func columnByID(id string) (columnDef, bool) {
+	for _, c := range columnRegistry {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return columnDef{}, false
+}
+
+// activeColumnDefs resolves the configured IDs, skipping unknown ones.
+func (m *Model) activeColumnDefs() []columnDef {
+	defs := make([]columnDef, 0, len(m.columns))
+	for _, id := range m.columns {
+		if def, ok := columnByID(id); ok {
+			defs = append(defs, def)
+		}
+	}
+	if len(defs) == 0 {
+		for _, id := range defaultColumns {
+			def, _ := columnByID(id)
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// columnWidths sizes the active columns for the current terminal width.
+// Fixed columns keep their width; flexible ones share what is left.
+func (m *Model) columnWidths(defs []columnDef) []int {
+	widths := make([]int, len(defs))
+	used := 2 // table border
+	flex := 0
+	for i, d := range defs {
+		widths[i] = d.Width
+		if d.ID == "program" && m.treeMode {
+			// Tree mode needs room for the guides
+			widths[i] = 30
+		}
+		used += 2 // cell padding
+		if d.Flex {
+			flex++
+			continue
+		}
+		used += widths[i]
+	}
+
+	if flex == 0 || m.width == 0 {
+		return widths
+	}
+
+	share := (m.width - used) / flex
+	if share < minFlexWidth {
+		share = minFlexWidth
+	}
+	for i, d := range defs {
+		if d.Flex {
+			widths[i] = share
+		}
+	}
+	return widths
+}
+
+// buildColumns constructs the table columns with sort indicators applied.
+func (m *Model) buildColumns() []table.Column {
+	defs := m.activeColumnDefs()
+	widths := m.columnWidths(defs)
+
+	sortIndicator := "↓"
+	if !m.sorter.Descending {
+		sortIndicator = "↑"
+	}
+
+	columns := make([]table.Column, len(defs))
+	for i, d := range defs {
+		title := d.Title
+		if d.Sortable && d.Sort == m.sorter.Column {
+			title += " " + sortIndicator
+		}
+		columns[i] = table.Column{Title: title, Width: widths[i]}
+	}
+	return columns
+}
+
+// buildRow renders one record with the active columns.
+func (m *Model) buildRow(defs []columnDef, widths []int, c cell) table.Row {
+	row := make(table.Row, len(defs))
+	for i, d := range defs {
+		v := truncate(d.Value(m, c), widths[i])
+		if d.Style != nil {
+			v = d.Style(c, v)
+		}
+		row[i] = v
+	}
+	return row
+}
+
+// thresholdStyle colors s yellow above med and red above high.
+func thresholdStyle(v, med, high float64, s string) string {
+	if v > high {
+		return highCPUStyle.Render(s)
+	} else if v > med {
+		return medCPUStyle.Render(s)
+	}
+	return s
+}
+
+// truncate shortens s to width runes, marking the cut with "...".
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
+
+// formatStart shows when the process started: clock time if today,
+// otherwise the date.
+func (m *Model) formatStart(r model.ProcRec) string {
+	if m.uptime <= 0 || model.DefaultHZ <= 0 {
+		return "-"
+	}
+	boot := time.Now().Add(-time.Duration(m.uptime * float64(time.Second)))
+	started := boot.Add(time.Duration(r.StartTime) * time.Second / time.Duration(model.DefaultHZ))
+
+	if time.Since(started) < 24*time.Hour && started.Day() == time.Now().Day() {
+		return started.Format("15:04")
+	}
+	return started.Format("Jan02")
+}
+
+// programName derives the display program name from a record.
+func programName(r model.ProcRec) string {
+	if r.Cmd == "" {
+		return "[" + r.Comm + "]"
+	}
+	fullPath, _, _ := strings.Cut(r.Cmd, " ")
+	if idx := strings.LastIndex(fullPath, "/"); idx >= 0 {
+		return fullPath[idx+1:]
+	}
+	return fullPath
+}
+
+// commandArgs returns the arguments of the command line, without the program.
+func commandArgs(r model.ProcRec) string {
+	_, args, _ := strings.Cut(r.Cmd, " ")
+	return args
+}
+
+// columnChoices returns the active column IDs followed by the inactive ones,
+// which is the list shown in the columns screen.
+func (m *Model) columnChoices() []string {
+	active := make(map[string]bool, len(m.columns))
+	choices := make([]string, 0, len(columnRegistry))
+	for _, id := range m.columns {
+		active[id] = true
+		choices = append(choices, id)
+	}
+	for _, c := range columnRegistry {
+		if !active[c.ID] {
+			choices = append(choices, c.ID)
+		}
+	}
+	return choices
+}
+
+func (m *Model) columnActive(id string) int {
+	for i, c := range m.columns {
+		if c == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveColumns persists the layout and redraws the table.
+func (m *Model) saveColumns() {
+	m.cfg.Columns = append([]string(nil), m.columns...)
+	config.SaveSettings(m.cfg)
+	m.syncFDCounting()
+	m.updateTable()
+}
+
+// syncFDCounting has the sampler count descriptors only while the FDS
+// column is shown.
+func (m *Model) syncFDCounting() {
+	if m.sampler != nil {
+		m.sampler.SetCountFDs(m.columnActive("fds") >= 0)
+	}
+}
+
+func (m Model) handleColumnsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	choices := m.columnChoices()
+	if m.colCursor >= len(choices) {
+		m.colCursor = len(choices) - 1
+	}
+	id := choices[m.colCursor]
+	pos := m.columnActive(id)
+
+	switch msg.String() {
+	case "esc", "q":
+		m.mode = settingsMode
+		return m, nil
+
+	case "up", "k":
+		if m.colCursor > 0 {
+			m.colCursor--
+		}
+	case "down", "j":
+		if m.colCursor < len(choices)-1 {
+			m.colCursor++
+		}
+
+	case " ", "enter":
+		if pos >= 0 {
+			if len(m.columns) == 1 {
+				return m, m.showStatus("At least one column is required", true)
+			}
+			m.columns = append(m.columns[:pos:pos], m.columns[pos+1:]...)
+		} else {
+			m.columns = append(m.columns, id)
+			m.colCursor = len(m.columns) - 1
+		}
+		m.saveColumns()
+
+	case "K", "shift+up", "[":
+		if pos > 0 {
+			m.columns[pos-1], m.columns[pos] = m.columns[pos], m.columns[pos-1]
+			m.colCursor--
+			m.saveColumns()
+		}
+	case "J", "shift+down", "]":
+		if pos >= 0 && pos < len(m.columns)-1 {
+			m.columns[pos+1], m.columns[pos] = m.columns[pos], m.columns[pos+1]
+			m.colCursor++
+			m.saveColumns()
+		}
+
+	case "r":
+		m.columns = append([]string(nil), defaultColumns...)
+		m.colCursor = 0
+		m.saveColumns()
+	}
+	return m, nil
+}
+
+func (m Model) renderColumns() string {
+	var b strings.Builder
+
+	b.WriteString("===== COLUMNS =====\n\n")
+
+	for i, id := range m.columnChoices() {
+		def, _ := columnByID(id)
+		mark := "[ ]"
+		if m.columnActive(id) >= 0 {
+			mark = "[x]"
+		}
+		sel := " "
+		if i == m.colCursor {
+			sel = ">"
+		}
+		line := fmt.Sprintf("%s %s %-8s %s", sel, mark, def.Title, def.ID)
+		if i == m.colCursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\nActions:\n")
+	b.WriteString(" space  Show/hide column\n")
+	b.WriteString(" K / J  Move column left/right\n")
+	b.WriteString(" r      Reset to defaults\n")
+	b.WriteString(" q      Back\n")
+
+	if m.statusText != "" && m.statusError {
+		b.WriteString("\n" + m.renderStatus() + "\n")
+	}
+	return b.String()
+}
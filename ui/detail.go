@@ -0,0 +1,266 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"sentinel/model"
+	"sentinel/proc"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// detailTabs are the sections of the process detail pane, in display order.
+var detailTabs = []string{"Status", "Limits", "FDs", "Maps", "Environ", "Files"}
+
+// openDetail switches to the detail pane for the process under the cursor.
+func (m Model) openDetail() (tea.Model, tea.Cmd) {
+	key, ok := m.selectedRecordKey()
+	if !ok {
+		return m, nil
+	}
+	m.detailKey = key
+	m.detailTab = 0
+	m.mode = detailMode
+	m.loadDetail()
+	return m, nil
+}
+
+func (m Model) handleDetailMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "enter":
+		m.mode = normalMode
+		m.detailLines = nil
+		return m, nil
+
+	case "tab", "right", "l":
+		m.detailTab = (m.detailTab + 1) % len(detailTabs)
+		m.loadDetail()
+	case "shift+tab", "left", "h":
+		m.detailTab = (m.detailTab - 1 + len(detailTabs)) % len(detailTabs)
+		m.loadDetail()
+	case "1", "2", "3", "4", "5", "6":
+		m.detailTab = int(msg.String()[0] - '1')
+		m.loadDetail()
+	case "r":
+		m.loadDetail()
+
+	case "up", "k":
+		if m.detailScroll > 0 {
+			m.detailScroll--
+		}
+	case "down", "j":
+		if m.detailScroll < len(m.detailLines)-1 {
+			m.detailScroll++
+		}
+	case "pgup":
+		m.detailScroll -= m.detailPageSize()
+		if m.detailScroll < 0 {
+			m.detailScroll = 0
+		}
+	case "pgdown":
+		m.detailScroll += m.detailPageSize()
+		if m.detailScroll > len(m.detailLines)-1 {
+			m.detailScroll = len(m.detailLines) - 1
+		}
+	case "home", "g":
+		m.detailScroll = 0
+	case "end", "G":
+		m.detailScroll = len(m.detailLines) - 1
+	}
+
+	if m.detailScroll < 0 {
+		m.detailScroll = 0
+	}
+	return m, nil
+}
+
+// loadDetail reads the /proc files of the current tab.
+func (m *Model) loadDetail() {
+	m.detailScroll = 0
+	m.detailErr = ""
+
+	if err := verifyIdentity(m.detailKey); err != nil {
+		m.detailLines = nil
+		m.detailErr = err.Error()
+		return
+	}
+
+	var lines []string
+	var err error
+	pid := m.detailKey.Pid
+
+	switch detailTabs[m.detailTab] {
+	case "Status":
+		lines, err = statusLines(pid)
+	case "Limits":
+		lines, err = limitLines(pid)
+	case "FDs":
+		lines, err = fdLines(pid)
+	case "Maps":
+		lines, err = mapLines(pid)
+	case "Environ":
+		lines, err = proc.ReadEnviron(pid)
+	case "Files":
+		lines = fileLines(pid)
+	}
+
+	m.detailLines = lines
+	if err != nil {
+		m.detailErr = err.Error()
+	}
+}
+
+func statusLines(pid int) ([]string, error) {
+	fields, err := proc.ReadStatus(pid)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(fields))
+	for _, f := range fields {
+		lines = append(lines, fmt.Sprintf("%-28s %s", f.Key+":", f.Value))
+	}
+	return lines, nil
+}
+
+func limitLines(pid int) ([]string, error) {
+	limits, err := proc.ReadLimits(pid)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{fmt.Sprintf("%-26s %-20s %-20s %s", "LIMIT", "SOFT", "HARD", "UNITS")}
+	for _, l := range limits {
+		lines = append(lines, fmt.Sprintf("%-26s %-20s %-20s %s", l.Name, l.Soft, l.Hard, l.Units))
+	}
+	return lines, nil
+}
+
+func fdLines(pid int) ([]string, error) {
+	fds, err := proc.ReadFDs(pid)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{fmt.Sprintf("%d open descriptors", len(fds))}
+	for _, fd := range fds {
+		lines = append(lines, fmt.Sprintf("%6d -> %s", fd.FD, fd.Target))
+	}
+	return lines, nil
+}
+
+func mapLines(pid int) ([]string, error) {
+	maps, err := proc.ReadMaps(pid)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{fmt.Sprintf("%-33s %-5s %9s  %s", "ADDRESS", "PERMS", "SIZE", "PATH")}
+	for _, mp := range maps {
+		path := mp.Path
+		if path == "" {
+			path = "[anon]"
+		}
+		lines = append(lines, fmt.Sprintf("%016x-%016x %-5s %9s  %s",
+			mp.Start, mp.End, mp.Perms, FormatKB(mp.SizeKB()), path))
+	}
+	return lines, nil
+}
+
+// fileLines shows exe, cwd, cmdline and the identity fields from stat.
+// Unreadable links are reported inline instead of failing the tab.
+func fileLines(pid int) []string {
+	orErr := func(s string, err error) string {
+		if err != nil {
+			return "(" + err.Error() + ")"
+		}
+		return s
+	}
+
+	lines := []string{
+		"exe:      " + orErr(proc.ReadExe(pid)),
+		"cwd:      " + orErr(proc.ReadCwd(pid)),
+		"cmdline:  " + proc.ReadCmdline(pid),
+	}
+
+	if st, ok := proc.ReadProcStat(pid); ok {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("ppid:     %d", st.PPid),
+			fmt.Sprintf("pgrp:     %d  session: %d  tty: %d", st.Pgrp, st.Session, st.TTYNr),
+			fmt.Sprintf("threads:  %d", st.NumThreads),
+			fmt.Sprintf("started:  %s after boot", FormatTime(st.StartTime, model.DefaultHZ)),
+			fmt.Sprintf("cpu:      last ran on %d, utime %s, stime %s",
+				st.Processor,
+				FormatTimeTicks(st.UTime, model.DefaultHZ),
+				FormatTimeTicks(st.STime, model.DefaultHZ)),
+			fmt.Sprintf("faults:   minor %d, major %d", st.MinFlt, st.MajFlt),
+		)
+	}
+	if n := proc.CountFDs(pid); n >= 0 {
+		lines = append(lines, fmt.Sprintf("fds:      %d", n))
+	}
+	return lines
+}
+
+// detailPageSize is the number of content lines visible in the detail pane.
+func (m Model) detailPageSize() int {
+	h := m.height - 8
+	if h < 5 {
+		h = 5
+	}
+	return h
+}
+
+func (m Model) renderDetail() string {
+	var b strings.Builder
+
+	title := titleStyle.Render(fmt.Sprintf("🔎 PID %d", m.detailKey.Pid))
+	b.WriteString(lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center).
+		Render(title))
+	b.WriteString("\n\n")
+
+	tabs := make([]string, len(detailTabs))
+	for i, name := range detailTabs {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if i == m.detailTab {
+			tabs[i] = selectedStyle.Render(label)
+		} else {
+			tabs[i] = keybindDescStyle.Render(label)
+		}
+	}
+	b.WriteString(strings.Join(tabs, "│"))
+	b.WriteString("\n\n")
+
+	if m.detailErr != "" {
+		b.WriteString(errorStyle.Render("Error: " + m.detailErr))
+		b.WriteString("\n")
+	}
+
+	end := m.detailScroll + m.detailPageSize()
+	if end > len(m.detailLines) {
+		end = len(m.detailLines)
+	}
+	// Cut by display width, keeping multi-byte and wide characters whole
+	tail := "..."
+	if m.width <= len(tail) {
+		tail = ""
+	}
+	for _, line := range m.detailLines[m.detailScroll:end] {
+		if m.width > 0 {
+			line = ansi.Truncate(line, m.width, tail)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(keybindDescStyle.Render(fmt.Sprintf(
+		"[tab/←→/1-6] Switch tab | [↑↓ PgUp PgDn] Scroll (%d/%d) | [r] Refresh | [esc] Back",
+		min(m.detailScroll+1, len(m.detailLines)), len(m.detailLines))))
+	return b.String()
+}
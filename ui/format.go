@@ -1,6 +1,9 @@
 package ui
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func FormatTimeTicks(ticks uint64, hz int) string {
 	totalCS := (ticks * 100) / uint64(hz)
@@ -60,3 +63,62 @@ func FormatTime(jiffies uint64, hz int) string {
 	}
 	return fmt.Sprintf("%d:%02d", mins, secs)
 }
+
+// FormatPercentBar draws a fixed-width bar filled proportionally to pct (0-100).
+func FormatPercentBar(pct float64, width int) string {
+	if pct < 0 {
+		pct = 0
+	} else if pct > 100 {
+		pct = 100
+	}
+	filled := int(pct*float64(width)/100.0 + 0.5)
+	return strings.Repeat("|", filled) + strings.Repeat(" ", width-filled)
+}
+
+// FormatRate formats a bytes/s rate with an appropriate unit (B, K, M, G)
+func FormatRate(bps float64) string {
+	switch {
+	case bps < 1:
+		return "0"
+	case bps < 1024:
+		return fmt.Sprintf("%.0fB", bps)
+	case bps < 1024*1024:
+		return fmt.Sprintf("%.1fK", bps/1024)
+	case bps < 1024*1024*1024:
+		return fmt.Sprintf("%.1fM", bps/1024/1024)
+	}
+	return fmt.Sprintf("%.2fG", bps/1024/1024/1024)
+}
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// FormatSparkline draws the last width values as a block sparkline.
+// Values are scaled to max; when max <= 0 the largest value is used.
+func FormatSparkline(values []float64, width int, max float64) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := len(values); i < width; i++ {
+		b.WriteRune(' ')
+	}
+	for _, v := range values {
+		idx := 0
+		if max > 0 && v > 0 {
+			idx = int(v / max * float64(len(sparkRunes)-1))
+			if idx >= len(sparkRunes) {
+				idx = len(sparkRunes) - 1
+			}
+		}
+		b.WriteRune(sparkRunes[idx])
+	}
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -8,6 +9,8 @@ import (
 
 	"sentinel/config"
 	"sentinel/model"
+	"sentinel/monitor"
+	"sentinel/proc"
 
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -23,6 +26,10 @@ type Model struct {
 	running     int
 	l1, l5, l15 float64
 	uptime      float64
+	cpu         proc.CPUUsage
+	cores       []proc.CPUUsage
+	mem         proc.MemInfo
+	history     *monitor.History
 	sorter      *model.Sorter
 	interval    time.Duration
 	width       int
@@ -37,8 +44,27 @@ type Model struct {
 	statusText  string
 	statusError bool
 
+	sampler *monitor.Sampler // nil outside Run
+
+	// Columns: active IDs in display order, and the identity of each table row
+	columns   []string
+	rowKeys   []model.ProcKey
+	colCursor int
+
+	// Tree view
+	treeMode  bool
+	collapsed map[model.ProcKey]bool
+
+	// Process detail pane
+	detailKey    model.ProcKey
+	detailTab    int
+	detailScroll int
+	detailLines  []string
+	detailErr    string
+
 	// Kill/Nice confirmation
 	selectedPID int
+	selectedKey model.ProcKey
 	niceValue   int
 
 	cfg                  *config.SentinelConfig
@@ -52,22 +78,8 @@ type Model struct {
 	addingWebhookStep int
 }
 
-func NewModel(interval time.Duration) Model {
-	columns := []table.Column{
-		{Title: "PID", Width: 7},
-		{Title: "USER", Width: 10},
-		{Title: "PROGRAM", Width: 15},
-		{Title: "%CPU", Width: 7},
-		{Title: "%MEM", Width: 7},
-		{Title: "VSIZE", Width: 9},
-		{Title: "RSS", Width: 9},
-		{Title: "S", Width: 3},
-		{Title: "TIME+", Width: 9},
-		{Title: "COMMAND", Width: 45},
-	}
-
+func NewModel(cfg *config.SentinelConfig, interval time.Duration) Model {
 	t := table.New(
-		table.WithColumns(columns),
 		table.WithFocused(true),
 		table.WithHeight(20),
 	)
@@ -90,8 +102,6 @@ func NewModel(interval time.Duration) Model {
 	ti.Placeholder = "filter by command or user..."
 	ti.CharLimit = 50
 
-	cfg, _ := config.LoadConfig()
-
 	cpuInput := textinput.New()
 	cpuInput.Placeholder = "CPU threshold %"
 	cpuInput.CharLimit = 4
@@ -113,12 +123,18 @@ func NewModel(interval time.Duration) Model {
 		whNames = append(whNames, name)
 	}
 
-	return Model{
+	columns := cfg.Columns
+	if len(columns) == 0 {
+		columns = append([]string(nil), defaultColumns...)
+	}
+
+	m := Model{
 		table:                t,
 		sorter:               model.NewSorter(),
 		interval:             interval,
 		filterInput:          ti,
 		mode:                 normalMode,
+		collapsed:            make(map[model.ProcKey]bool),
 		cfg:                  cfg,
 		webhookNames:         whNames,
 		cpuInput:             cpuInput,
@@ -126,7 +142,10 @@ func NewModel(interval time.Duration) Model {
 		webhookNameInput:     webhookName,
 		webhookURLInput:      webhookURL,
 		selectedWebhookIndex: 0,
+		columns:              columns,
 	}
+	m.table.SetColumns(m.buildColumns())
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
@@ -179,7 +198,7 @@ func openExportCSV() {
 		}
 
 		if stat.Size() == 0 {
-			header := "timestamp_ms,pid,user,comm,cpu_pct,mem_pct,vsize_kb,rss_kb,state,threads,time_plus,cmdline\n"
+			header := "timestamp_ms,pid,user,comm,cpu_pct,mem_pct,vsize_kb,rss_kb,state,threads,ppid,start_time,minflt,majflt,time_plus,cmdline\n"
 			_, err = f.WriteString(header)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "[ERROR] Failed to write header: %v\n", err)
@@ -214,7 +233,7 @@ func exportRecordCSV(t time.Time, r model.ProcRec) {
 	}
 
 	fmt.Fprintf(exportCSVFile,
-		"%d,%d,%s,%s,%.1f,%.1f,%d,%d,%s,%d,%s,%s\n",
+		"%d,%d,%s,%s,%.1f,%.1f,%d,%d,%s,%d,%d,%d,%d,%d,%s,%s\n",
 		t.UnixMilli(),
 		r.Pid,
 		r.User,
@@ -224,29 +243,57 @@ func exportRecordCSV(t time.Time, r model.ProcRec) {
 		r.VSizeKB,
 		r.RSSKB,
 		string(r.State),
+		r.Threads,
+		r.PPid,
+		r.StartTime,
+		r.MinFlt,
+		r.MajFlt,
 		timeStr,
 		cmdline,
 	)
 }
 
-// SendData is called by engine to push new data
-func SendData(p *tea.Program, records []model.ProcRec, tasks, running int, loads [3]float64, uptime float64) {
-	// Export CSV if enabled
-	openExportCSV()
-	if exportCSVFile != nil {
-		now := time.Now()
-		for _, r := range records {
-			exportRecordCSV(now, r)
+// Run starts the TUI on cfg and feeds it with snapshots from sampler until
+// the user quits or ctx is cancelled. The sampler must be running separately.
+func Run(ctx context.Context, cfg *config.SentinelConfig, sampler *monitor.Sampler) error {
+	m := NewModel(cfg, sampler.Interval())
+	m.sampler = sampler
+	m.syncFDCounting()
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	snaps, unsubscribe := sampler.Subscribe()
+	defer unsubscribe()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				p.Quit()
+				return
+			case snap, ok := <-snaps:
+				if !ok {
+					return
+				}
+				exportSnapshotCSV(snap)
+				p.Send(dataMsg{snap: snap})
+			}
 		}
+	}()
+
+	// Blocks until quit
+	if _, err := p.Run(); err != nil {
+		return err
 	}
+	return ctx.Err()
+}
 
-	p.Send(dataMsg{
-		records: records,
-		tasks:   tasks,
-		running: running,
-		l1:      loads[0],
-		l5:      loads[1],
-		l15:     loads[2],
-		uptime:  uptime,
-	})
+// exportSnapshotCSV appends every record of snap to the CSV export, if enabled.
+func exportSnapshotCSV(snap *monitor.Snapshot) {
+	openExportCSV()
+	if exportCSVFile == nil {
+		return
+	}
+	for _, r := range snap.Records {
+		exportRecordCSV(snap.Time, r)
+	}
 }
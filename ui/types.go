@@ -3,19 +3,16 @@ package ui
 import (
 	"time"
 
-	"sentinel/model"
+	"sentinel/monitor"
 )
 
 // Messages
 
 type tickMsg time.Time
 
+// dataMsg carries a read-only snapshot from the sampler.
 type dataMsg struct {
-	records     []model.ProcRec
-	tasks       int
-	running     int
-	l1, l5, l15 float64
-	uptime      float64
+	snap *monitor.Snapshot
 }
 
 type statusMsg struct {
@@ -39,4 +36,6 @@ const (
 	addWebhookMode
 	confirmDeleteWebhook
 	selectWebhookMode
+	detailMode
+	columnsMode
 )
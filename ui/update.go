@@ -29,26 +29,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleEditMEM(msg)
 		case addWebhookMode:
 			return m.handleAddWebhook(msg)
+		case detailMode:
+			return m.handleDetailMode(msg)
+		case columnsMode:
+			return m.handleColumnsMode(msg)
 		}
 		return m.handleKeyPress(msg)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.table.SetHeight(msg.Height - 12)
+		m.table.SetHeight(m.tableHeight())
+		m.updateTable()
 		return m, nil
 
 	case tickMsg:
 		return m, tickCmd(m.interval)
 
 	case dataMsg:
-		m.records = msg.records
-		m.tasks = msg.tasks
-		m.running = msg.running
-		m.l1 = msg.l1
-		m.l5 = msg.l5
-		m.l15 = msg.l15
-		m.uptime = msg.uptime
+		snap := msg.snap
+		m.records = snap.Records
+		m.tasks = snap.Tasks
+		m.running = snap.Running
+		m.l1 = snap.Loads[0]
+		m.l5 = snap.Loads[1]
+		m.l15 = snap.Loads[2]
+		m.uptime = snap.Uptime
+		m.cpu = snap.CPU
+		m.cores = snap.Cores
+		m.mem = snap.Mem
+		m.history = snap.History
+		if m.height > 0 {
+			m.table.SetHeight(m.tableHeight())
+		}
 		m.updateTable()
 		return m, nil
 
@@ -117,6 +130,36 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "t":
 		m.sorter.Toggle(model.SortByTIME)
 		m.updateTable()
+	case "i":
+		m.sorter.Toggle(model.SortByREAD)
+		m.updateTable()
+	case "o":
+		m.sorter.Toggle(model.SortByWRITE)
+		m.updateTable()
+
+	// Tree view
+	case "T", "f5":
+		m.treeMode = !m.treeMode
+		m.updateTable()
+		return m, nil
+	case " ":
+		if key, ok := m.selectedRecordKey(); ok && m.treeMode {
+			m.collapsed[key] = !m.collapsed[key]
+			m.updateTable()
+		}
+		return m, nil
+	case "-":
+		if key, ok := m.selectedRecordKey(); ok && m.treeMode {
+			m.collapsed[key] = true
+			m.updateTable()
+		}
+		return m, nil
+	case "+":
+		if m.treeMode {
+			m.collapsed = make(map[model.ProcKey]bool)
+			m.updateTable()
+		}
+		return m, nil
 
 	// Filtering
 	case "/":
@@ -126,14 +169,19 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Kill process
 	case "k":
-		if pid := m.getSelectedPID(); pid > 0 {
-			m.selectedPID = pid
+		if key, ok := m.selectedRecordKey(); ok {
+			m.selectedPID = key.Pid
+			m.selectedKey = key
 			m.mode = confirmKillMode
 		}
 
 	// Force kill
 	case "K":
-		if pid := m.getSelectedPID(); pid > 0 {
+		if key, ok := m.selectedRecordKey(); ok {
+			pid := key.Pid
+			if err := verifyIdentity(key); err != nil {
+				return m, m.showStatus(fmt.Sprintf(errorFmt, err), true)
+			}
 			if err := proc.ForceKillProcess(pid); err != nil {
 				return m, m.showStatus(fmt.Sprintf(errorFmt, err), true)
 			}
@@ -142,16 +190,18 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Renice (increase priority)
 	case "n":
-		if pid := m.getSelectedPID(); pid > 0 {
-			m.selectedPID = pid
+		if key, ok := m.selectedRecordKey(); ok {
+			m.selectedPID = key.Pid
+			m.selectedKey = key
 			m.niceValue = -5
 			m.mode = confirmNiceMode
 		}
 
 	// Renice (decrease priority)
 	case "N":
-		if pid := m.getSelectedPID(); pid > 0 {
-			m.selectedPID = pid
+		if key, ok := m.selectedRecordKey(); ok {
+			m.selectedPID = key.Pid
+			m.selectedKey = key
 			m.niceValue = 5
 			m.mode = confirmNiceMode
 		}
@@ -159,6 +209,9 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "s":
 		m.mode = settingsMode
 		return m, nil
+
+	case "enter":
+		return m.openDetail()
 	}
 
 	var cmd tea.Cmd
@@ -190,6 +243,10 @@ func (m Model) handleFilterMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleConfirmKill(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
+		if err := verifyIdentity(m.selectedKey); err != nil {
+			m.mode = normalMode
+			return m, m.showStatus(fmt.Sprintf(errorFmt, err), true)
+		}
 		if err := proc.TerminateProcess(m.selectedPID); err != nil {
 			m.mode = normalMode
 			return m, m.showStatus(fmt.Sprintf(errorFmt, err), true)
@@ -207,6 +264,10 @@ func (m Model) handleConfirmKill(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleConfirmNice(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
+		if err := verifyIdentity(m.selectedKey); err != nil {
+			m.mode = normalMode
+			return m, m.showStatus(fmt.Sprintf(errorFmt, err), true)
+		}
 		currentNice, _ := proc.GetProcessPriority(m.selectedPID)
 		newNice := currentNice + m.niceValue
 
@@ -270,11 +331,16 @@ func (m Model) handleSettingsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "c":
+		m.mode = columnsMode
+		m.colCursor = 0
+		return m, nil
+
 	case "w":
 		if len(m.webhookNames) > 0 {
 			name := m.webhookNames[m.selectedWebhookIndex]
 			m.cfg.ActiveWebhook = name
-			config.SaveConfig(m.cfg)
+			config.SaveSettings(m.cfg)
 		}
 		return m, nil
 
@@ -300,7 +366,7 @@ func (m Model) handleEditCPU(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		v := m.cpuInput.Value()
 		f, _ := strconv.ParseFloat(v, 64)
 		m.cfg.CPUThreshold = f
-		config.SaveConfig(m.cfg)
+		config.SaveSettings(m.cfg)
 		m.mode = settingsMode
 		return m, nil
 
@@ -320,7 +386,7 @@ func (m Model) handleEditMEM(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		v := m.memInput.Value()
 		f, _ := strconv.ParseFloat(v, 64)
 		m.cfg.MemThreshold = f
-		config.SaveConfig(m.cfg)
+		config.SaveSettings(m.cfg)
 		m.mode = settingsMode
 		return m, nil
 
@@ -354,8 +420,8 @@ func (m Model) handleAddWebhook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			url := m.webhookURLInput.Value()
 
 			if name != "" && url != "" {
-				m.cfg.Webhooks[name] = url
-				config.SaveConfig(m.cfg)
+				m.cfg.Webhooks[name] = config.Webhook{URL: url}
+				config.SaveSettings(m.cfg)
 
 				m.webhookNames = append(m.webhookNames, name)
 			}
@@ -387,150 +453,123 @@ func (m Model) handleAddWebhook(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// tableHeight returns the rows left for the process table once the title,
+// header, CPU and memory lines and the selected-process trend are drawn.
+func (m *Model) tableHeight() int {
+	h := m.height - 14 - m.cpuHeaderLines()
+	if h < 3 {
+		h = 3
+	}
+	return h
+}
+
 func (m *Model) updateTable() {
 	// Apply filter
 	filtered := m.applyFilter(m.records, m.filterText)
 
-	// Sort on a copy
-	sorted := make([]model.ProcRec, len(filtered))
-	copy(sorted, filtered)
-	m.sorter.Sort(sorted)
+	var cells []cell
+	if m.treeMode {
+		cells = m.treeCells(filtered)
+	} else {
+		// Sort on a copy
+		sorted := make([]model.ProcRec, len(filtered))
+		copy(sorted, filtered)
+		m.sorter.Sort(sorted)
+		cells = m.flatCells(sorted)
+	}
 
-	// Update column headers
-	m.table.SetColumns(m.buildColumns())
+	defs := m.activeColumnDefs()
+	columns := m.buildColumns()
+	widths := make([]int, len(columns))
+	for i := range columns {
+		widths[i] = columns[i].Width
+	}
 
 	// Preserve selection
-	selectedPID := m.getSelectedPID()
-	rows := m.buildRows(sorted)
-	m.table.SetRows(rows)
-	m.restoreSelection(rows, selectedPID)
-}
+	selected, hadSelection := m.selectedRecordKey()
 
-// buildColumns constructs the table columns with sort indicators applied.
-func (m *Model) buildColumns() []table.Column {
-	columns := m.table.Columns()
-	sortIndicator := "↓"
-	if !m.sorter.Descending {
-		sortIndicator = "↑"
+	rows := make([]table.Row, len(cells))
+	keys := make([]model.ProcKey, len(cells))
+	for i, c := range cells {
+		rows[i] = m.buildRow(defs, widths, c)
+		keys[i] = c.rec.Key()
 	}
 
-	columns[0].Title = "PID"
-	columns[1].Title = "USER"
-	columns[2].Title = "PROGRAM"
-	columns[3].Title = "%CPU"
-	columns[4].Title = "%MEM"
-	columns[5].Title = "VSIZE"
-	columns[6].Title = "RSS"
-	columns[7].Title = "S"
-	columns[8].Title = "TIME+"
-	columns[9].Title = "COMMAND"
-
-	switch m.sorter.Column {
-	case model.SortByPID:
-		columns[0].Title = "PID " + sortIndicator
-	case model.SortByUSER:
-		columns[1].Title = "USER " + sortIndicator
-	case model.SortByCPUCol:
-		columns[3].Title = "%CPU " + sortIndicator
-	case model.SortByMEM:
-		columns[4].Title = "%MEM " + sortIndicator
-	case model.SortByVSIZE:
-		columns[5].Title = "VSIZE " + sortIndicator
-	case model.SortByRSS:
-		columns[6].Title = "RSS " + sortIndicator
-	case model.SortByTIME:
-		columns[8].Title = "TIME+ " + sortIndicator
+	// Clear rows first: the column count may have changed
+	m.table.SetRows(nil)
+	m.table.SetColumns(columns)
+	m.table.SetRows(rows)
+	m.rowKeys = keys
+
+	if hadSelection {
+		m.restoreSelection(selected)
 	}
-	return columns
 }
 
-// buildRows converts sorted process records into table rows with styling and truncation.
-func (m *Model) buildRows(sorted []model.ProcRec) []table.Row {
-	rows := make([]table.Row, 0, len(sorted))
+// flatCells converts sorted process records into table cells.
+func (m *Model) flatCells(sorted []model.ProcRec) []cell {
+	cells := make([]cell, 0, len(sorted))
 	for _, r := range sorted {
 		if !r.Alive {
 			continue
 		}
-
-		cpu := fmt.Sprintf("%.1f", r.CPU)
-		mem := fmt.Sprintf("%.1f", r.PMem)
-
-		if r.CPU > 50 {
-			cpu = highCPUStyle.Render(cpu)
-		} else if r.CPU > 20 {
-			cpu = medCPUStyle.Render(cpu)
-		}
-
-		if r.PMem > 10 {
-			mem = highCPUStyle.Render(mem)
-		} else if r.PMem > 5 {
-			mem = medCPUStyle.Render(mem)
-		}
-
-		program, args := m.programAndArgs(r)
-
-		timeStr := FormatTimeTicks(r.CurProcTime, model.DefaultHZ)
-
-		rows = append(rows, table.Row{
-			fmt.Sprintf("%d", r.Pid),
-			r.User,
-			program,
-			cpu,
-			mem,
-			FormatKB(r.VSizeKB),
-			FormatKB(r.RSSKB),
-			string(r.State),
-			timeStr,
-			args,
+		cells = append(cells, cell{
+			rec:   r,
+			cpu:   r.CPU,
+			mem:   r.PMem,
+			rssKB: r.RSSKB,
+			label: programName(r),
 		})
-
-		if len(rows) >= model.MaxRows {
+		if len(cells) >= model.MaxRows {
 			break
 		}
 	}
-	return rows
+	return cells
 }
 
-// programAndArgs derives the display program name and arguments from a record.
-func (m *Model) programAndArgs(r model.ProcRec) (string, string) {
-	program := r.Comm
-	args := ""
-
-	if r.Cmd != "" {
-		parts := strings.SplitN(r.Cmd, " ", 2)
-		if len(parts) > 0 {
-			fullPath := parts[0]
-			if idx := strings.LastIndex(fullPath, "/"); idx >= 0 {
-				program = fullPath[idx+1:]
-			} else {
-				program = fullPath
-			}
+// treeCells renders records as a process tree. Collapsed nodes show the
+// CPU/MEM/RSS of their whole subtree and a "+N" marker with the hidden count.
+func (m *Model) treeCells(records []model.ProcRec) []cell {
+	roots := model.BuildTree(records, m.sorter)
+	flat := model.FlattenTree(roots, m.collapsed)
+
+	cells := make([]cell, 0, len(flat))
+	for _, tr := range flat {
+		r := tr.Node.Rec
+		c := cell{
+			rec:   r,
+			cpu:   r.CPU,
+			mem:   r.PMem,
+			rssKB: r.RSSKB,
+			label: tr.Prefix + programName(r),
 		}
-		if len(parts) > 1 {
-			args = parts[1]
+		if tr.Collapsed {
+			c.cpu = tr.Node.SubtreeCPU
+			c.mem = tr.Node.SubtreePMem
+			c.rssKB = tr.Node.SubtreeRSSKB
+			c.label += fmt.Sprintf(" +%d", tr.Node.SubtreeCount-1)
 		}
-	} else {
-		program = "[" + r.Comm + "]"
-	}
+		cells = append(cells, c)
 
-	if len(program) > 15 {
-		program = program[:12] + "..."
-	}
-	if len(args) > 45 {
-		args = args[:42] + "..."
+		if len(cells) >= model.MaxRows {
+			break
+		}
 	}
-	return program, args
+	return cells
 }
 
-// restoreSelection moves the cursor back to the previously selected PID if present.
-func (m *Model) restoreSelection(rows []table.Row, selectedPID int) {
-	if selectedPID <= 0 || len(rows) == 0 {
-		return
+// formatIO renders an I/O rate, or "-" when /proc/<pid>/io is not readable.
+func (m *Model) formatIO(r model.ProcRec, bps float64) string {
+	if !r.IOValid {
+		return "-"
 	}
-	for i := range rows {
-		var pid int
-		fmt.Sscanf(rows[i][0], "%d", &pid)
-		if pid == selectedPID {
+	return FormatRate(bps)
+}
+
+// restoreSelection moves the cursor back to the previously selected process if present.
+func (m *Model) restoreSelection(key model.ProcKey) {
+	for i := range m.rowKeys {
+		if m.rowKeys[i] == key {
 			m.table.SetCursor(i)
 			break
 		}
@@ -563,14 +602,33 @@ func (m *Model) applyFilter(records []model.ProcRec, text string) []model.ProcRe
 }
 
 func (m Model) getSelectedPID() int {
-	selected := m.table.SelectedRow()
-	if len(selected) == 0 {
+	key, ok := m.selectedRecordKey()
+	if !ok {
 		return 0
 	}
+	return key.Pid
+}
 
-	var pid int
-	fmt.Sscanf(selected[0], "%d", &pid)
-	return pid
+// selectedRecordKey returns the identity of the process under the cursor.
+func (m Model) selectedRecordKey() (model.ProcKey, bool) {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.rowKeys) {
+		return model.ProcKey{}, false
+	}
+	return m.rowKeys[idx], true
+}
+
+// verifyIdentity checks that key.Pid still belongs to the same process,
+// so a signal is never sent to an unrelated process that reused the PID.
+func verifyIdentity(key model.ProcKey) error {
+	st, ok := proc.ReadProcStat(key.Pid)
+	if !ok {
+		return fmt.Errorf("process %d no longer exists", key.Pid)
+	}
+	if st.StartTime != key.StartTime {
+		return fmt.Errorf("PID %d was reused by another process", key.Pid)
+	}
+	return nil
 }
 
 func (m Model) showStatus(text string, isError bool) tea.Cmd {
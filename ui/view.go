@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"sentinel/monitor"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -19,6 +21,10 @@ func (m Model) View() string {
 		return "Edit MEM Threshold:\n\n" + m.memInput.View() + "\n\n[enter=save, esc=cancel]"
 	case addWebhookMode:
 		return m.renderAddWebhook()
+	case detailMode:
+		return m.renderDetail()
+	case columnsMode:
+		return m.renderColumns()
 	}
 
 	var b strings.Builder
@@ -28,6 +34,8 @@ func (m Model) View() string {
 	b.WriteString("\n\n")
 	b.WriteString(baseStyle.Render(m.table.View()))
 	b.WriteString("\n")
+	b.WriteString(m.renderSelectedTrend())
+	b.WriteString("\n")
 
 	if m.mode == normalMode {
 		b.WriteString(m.renderQuickHelp())
@@ -83,17 +91,138 @@ func (m Model) renderHeader() string {
 		direction,
 	)
 
+	if m.treeMode {
+		header += " | " + sortedColumnStyle.Render("Tree")
+	}
+
 	if m.filterText != "" {
 		header += fmt.Sprintf(" | Filter: %s",
 			lipgloss.NewStyle().Foreground(lipgloss.Color("green")).Render(m.filterText))
 	}
+
+	var sys monitor.Series
+	if m.history != nil {
+		sys = m.history.System()
+	}
+
+	header += "\n" + fmt.Sprintf(
+		"CPU: %5.1f%% %s | us %.1f sy %.1f ni %.1f | io %.1f | irq %.1f si %.1f | st %.1f",
+		m.cpu.Busy, FormatSparkline(sys.CPU, sparkWidth, 100),
+		m.cpu.User, m.cpu.System, m.cpu.Nice,
+		m.cpu.IOWait, m.cpu.IRQ, m.cpu.SoftIRQ, m.cpu.Steal,
+	)
+
+	if cores := m.renderCores(); cores != "" {
+		header += "\n" + cores
+	}
+
+	header += "\n" + m.renderMemory()
+	if len(sys.RSSKB) > 0 {
+		header += " " + FormatSparkline(sys.RSSKB, sparkWidth, float64(m.mem.MemTotalKB))
+	}
 	return header
 }
 
+// sparkWidth is the number of samples drawn in header and trend sparklines.
+const sparkWidth = 30
+
+// renderSelectedTrend draws CPU, RSS and disk I/O sparklines for the
+// process under the cursor.
+func (m Model) renderSelectedTrend() string {
+	key, ok := m.selectedRecordKey()
+	if !ok || m.history == nil {
+		return keybindDescStyle.Render("No process selected")
+	}
+	hist, ok := m.history.Process(key)
+	if !ok || len(hist.CPU) == 0 {
+		return keybindDescStyle.Render(fmt.Sprintf("PID %d: collecting history...", key.Pid))
+	}
+
+	last := func(v []float64) float64 { return v[len(v)-1] }
+	return fmt.Sprintf("PID %d  CPU %s %5.1f%%  RSS %s %s  R %s %s  W %s %s",
+		key.Pid,
+		FormatSparkline(hist.CPU, sparkWidth, 100), last(hist.CPU),
+		FormatSparkline(hist.RSSKB, sparkWidth/2, 0), FormatKB(int64(last(hist.RSSKB))),
+		FormatSparkline(hist.ReadBps, sparkWidth/3, 0), FormatRate(last(hist.ReadBps)),
+		FormatSparkline(hist.WriteBps, sparkWidth/3, 0), FormatRate(last(hist.WriteBps)),
+	)
+}
+
+// renderMemory shows used vs available memory and swap from /proc/meminfo.
+// "Used" excludes reclaimable page cache, unlike %MEM of MemTotal.
+func (m Model) renderMemory() string {
+	used := fmt.Sprintf("%s/%s (%.1f%%)",
+		FormatKB(m.mem.UsedKB()), FormatKB(m.mem.MemTotalKB), m.mem.UsedPercent())
+	if m.cfg.SysMemThreshold > 0 && m.mem.UsedPercent() >= m.cfg.SysMemThreshold {
+		used = highCPUStyle.Render(used)
+	}
+
+	line := fmt.Sprintf("Mem: %s | avail %s | buff %s | cache %s | dirty %s",
+		used,
+		FormatKB(m.mem.MemAvailableKB),
+		FormatKB(m.mem.BuffersKB),
+		FormatKB(m.mem.CacheKB()),
+		FormatKB(m.mem.DirtyKB),
+	)
+
+	if m.mem.SwapTotalKB > 0 {
+		line += fmt.Sprintf(" | Swap: %s/%s (%.1f%%)",
+			FormatKB(m.mem.SwapUsedKB()), FormatKB(m.mem.SwapTotalKB), m.mem.SwapUsedPercent())
+	} else {
+		line += " | Swap: off"
+	}
+
+	if m.mem.HugePagesTotal > 0 {
+		line += fmt.Sprintf(" | HugePages: %d/%d free",
+			m.mem.HugePagesFree, m.mem.HugePagesTotal)
+	}
+	return line
+}
+
+// coresPerLine is how many per-core meters are drawn on each header line.
+const coresPerLine = 4
+
+// renderCores draws one usage meter per core, coresPerLine per row.
+func (m Model) renderCores() string {
+	if len(m.cores) <= 1 {
+		return ""
+	}
+
+	var lines []string
+	var cur []string
+	for i, c := range m.cores {
+		bar := FormatPercentBar(c.Busy, 10)
+		if c.Busy > 80 {
+			bar = highCPUStyle.Render(bar)
+		} else if c.Busy > 50 {
+			bar = medCPUStyle.Render(bar)
+		}
+		cur = append(cur, fmt.Sprintf("%3d[%s%5.1f%%]", i, bar, c.Busy))
+		if len(cur) == coresPerLine {
+			lines = append(lines, strings.Join(cur, " "))
+			cur = nil
+		}
+	}
+	if len(cur) > 0 {
+		lines = append(lines, strings.Join(cur, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cpuHeaderLines returns the number of lines the CPU summary adds to the header.
+func (m Model) cpuHeaderLines() int {
+	n := 1
+	if len(m.cores) > 1 {
+		n += (len(m.cores) + coresPerLine - 1) / coresPerLine
+	}
+	return n
+}
+
 func (m Model) renderQuickHelp() string {
 	quickHelp := fmt.Sprintf(
-		"%s Sort | %s Filter | %s Actions | %s Settings | %s Help | %s Quit",
-		keybindStyle.Render("[c/m/p/u/v/r/t]"),
+		"%s Sort | %s Tree | %s Filter | %s Actions | %s Settings | %s Help | %s Quit",
+		keybindStyle.Render("[c/m/p/u/v/r/i/o/t]"),
+		keybindStyle.Render("[T]"),
 		keybindStyle.Render("[/]"),
 		keybindStyle.Render("[k/n]"),
 		keybindStyle.Render("[s]"),
@@ -158,10 +287,22 @@ func (m Model) renderHelp() string {
 				{"u", "Sort by USER"},
 				{"v", "Sort by VSIZE"},
 				{"r", "Sort by RSS"},
+				{"i", "Sort by disk READ/s"},
+				{"o", "Sort by disk WRITE/s"},
 				{"t", "Sort by TIME+"},
 				{"", "Press same key to toggle ascending/descending"},
 			},
 		},
+		{
+			title: "🌳 TREE VIEW",
+			keys: []struct{ key, desc string }{
+				{"T/F5", "Toggle process tree"},
+				{"Space", "Collapse/expand selected subtree"},
+				{"-", "Collapse selected subtree"},
+				{"+", "Expand all subtrees"},
+				{"", "Collapsed rows show CPU/MEM/RSS of the whole subtree"},
+			},
+		},
 		{
 			title: "🔍 FILTERING",
 			keys: []struct{ key, desc string }{
@@ -178,6 +319,7 @@ func (m Model) renderHelp() string {
 				{"K", "Send SIGKILL (force kill)"},
 				{"n", "Increase priority (nice -5)"},
 				{"N", "Decrease priority (nice +5)"},
+				{"Enter", "Show details (status, limits, fds, maps, env)"},
 				{"", "Requires appropriate permissions"},
 			},
 		},
@@ -232,7 +374,9 @@ func (m Model) renderSettings() string {
 	b.WriteString("===== SETTINGS =====\n\n")
 
 	b.WriteString(fmt.Sprintf("CPU Threshold: %.0f\n", m.cfg.CPUThreshold))
-	b.WriteString(fmt.Sprintf("MEM Threshold: %.0f\n\n", m.cfg.MemThreshold))
+	b.WriteString(fmt.Sprintf("MEM Threshold: %.0f\n", m.cfg.MemThreshold))
+	b.WriteString(fmt.Sprintf("System MEM Threshold: %.0f\n", m.cfg.SysMemThreshold))
+	b.WriteString(fmt.Sprintf("Swap Threshold: %.0f\n\n", m.cfg.SwapThreshold))
 
 	b.WriteString("Webhooks:\n")
 	for i, name := range m.webhookNames {
@@ -244,8 +388,8 @@ func (m Model) renderSettings() string {
 		if i == m.selectedWebhookIndex {
 			sel = ">"
 		}
-		url := m.cfg.Webhooks[name]
-		b.WriteString(fmt.Sprintf("%s %s %s → %s\n", sel, marker, name, url))
+		wh := m.cfg.Webhooks[name]
+		b.WriteString(fmt.Sprintf("%s %s %s [%s] → %s\n", sel, marker, name, wh.Kind(), wh.URL))
 	}
 
 	b.WriteString("\nActions:\n")
@@ -254,6 +398,7 @@ func (m Model) renderSettings() string {
 	b.WriteString(" a  Add Webhook\n")
 	b.WriteString(" d  Delete Webhook\n")
 	b.WriteString(" w  Set Selected as Active\n")
+	b.WriteString(" c  Configure Columns\n")
 	b.WriteString(" q  Back\n")
 
 	return b.String()